@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/pflag"
+)
+
+// applyConfigFile sets the flags from the YAML config file mapping the flag names to their values, like:
+//
+//	namespace: myproject
+//	parallelism: 4
+//	hooks: jobs
+//	keep-annotations: [openshift.io/generated-by]
+//
+// The flags given on the command line override the config file values.
+func applyConfigFile(flags *pflag.FlagSet, path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("invalid config file %q: %v", path, err)
+	}
+
+	names := []string{}
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		flag := flags.Lookup(name)
+		if flag == nil || name == "config" {
+			return fmt.Errorf("invalid config file %q: unknown flag %q", path, name)
+		}
+		if flag.Changed {
+			continue
+		}
+		var value string
+		switch v := values[name].(type) {
+		case []interface{}:
+			items := []string{}
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			value = strings.Join(items, ",")
+		case nil:
+			continue
+		default:
+			value = fmt.Sprint(v)
+		}
+		if err := flags.Set(name, value); err != nil {
+			return fmt.Errorf("invalid config file %q: invalid value %q for %q: %v", path, value, name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestApplyConfigFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		args   []string
+		// expected are the expected flag values after the config file is applied.
+		expected    map[string]string
+		expectedErr string
+	}{
+		{
+			name:   "config applies to unset flags",
+			config: "namespace: shop\nparallelism: 4\nhooks: jobs\nkeep-annotations: [openshift.io/generated-by, example.com/owner]\n",
+			expected: map[string]string{
+				"namespace":        "shop",
+				"parallelism":      "4",
+				"hooks":            "jobs",
+				"keep-annotations": "[openshift.io/generated-by,example.com/owner]",
+			},
+		},
+		{
+			name:     "flags override the config",
+			config:   "namespace: shop\nparallelism: 4\n",
+			args:     []string{"--parallelism=2"},
+			expected: map[string]string{"namespace": "shop", "parallelism": "2"},
+		},
+		{
+			name:        "unknown key",
+			config:      "namespace: shop\nparalelism: 4\n",
+			expectedErr: `unknown flag "paralelism"`,
+		},
+		{
+			name:        "config key",
+			config:      "config: other.yaml\n",
+			expectedErr: `unknown flag "config"`,
+		},
+		{
+			name:        "invalid value",
+			config:      "parallelism: many\n",
+			expectedErr: `invalid value "many" for "parallelism"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := ioutil.WriteFile(path, []byte(test.config), 0644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			cmd := NewMigrateCommand(&bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{})
+			if err := cmd.ParseFlags(test.args); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			err := applyConfigFile(cmd.Flags(), path)
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			values := map[string]string{}
+			for name := range test.expected {
+				values[name] = cmd.Flags().Lookup(name).Value.String()
+			}
+			if !reflect.DeepEqual(values, test.expected) {
+				t.Errorf("expected flag values %v, got %v", test.expected, values)
+			}
+		})
+	}
+}
+
+func TestCompleteStreamsConfigFile(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        string
+		expectedColor bool
+	}{
+		{
+			name:          "colors on terminal",
+			config:        "parallelism: 4\n",
+			expectedColor: true,
+		},
+		{
+			name:   "no-color in the config file",
+			config: "no-color: true\n",
+		},
+	}
+
+	defer func(f func(interface{}) bool) { isTerminal = f }(isTerminal)
+	isTerminal = func(interface{}) bool { return true }
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := ioutil.WriteFile(path, []byte(test.config), 0644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			m := &MigrateOptions{Output: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}, configFile: path}
+			cmd := &cobra.Command{}
+			cmd.Flags().BoolVar(&m.NoColor, "no-color", false, "")
+			cmd.Flags().IntVar(&m.Parallelism, "parallelism", 1, "")
+			if err := m.completeStreams(cmd); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if colored := m.color.Red("ERROR:").String() != "ERROR:"; colored != test.expectedColor {
+				t.Errorf("expected colors %t, got %t", test.expectedColor, colored)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	osappsv1 "github.com/openshift/api/apps/v1"
+)
+
+// diffField is a field compared between the deployment config and the deployment.
+type diffField struct {
+	name       string
+	dc         string
+	deployment string
+}
+
+// printDiff prints the unified diff of the fields that matter for the behavior of the application between
+// the deployment config and the converted deployment: the strategy, replicas, triggers and container images.
+func (m *MigrateOptions) printDiff(out io.Writer, dc *osappsv1.DeploymentConfig, deployment *appsv1.Deployment) {
+	fmt.Fprintf(out, "%s\n", m.color.Red("--- deploymentconfig.apps.openshift.io/"+dc.Namespace+"/"+dc.Name))
+	fmt.Fprintf(out, "%s\n", m.color.Green("+++ deployment.apps/"+deployment.Namespace+"/"+deployment.Name))
+	for _, field := range diffFields(dc, deployment) {
+		if field.dc == field.deployment {
+			fmt.Fprintf(out, "  %s: %s\n", field.name, field.dc)
+			continue
+		}
+		if len(field.dc) > 0 {
+			fmt.Fprintf(out, "%s\n", m.color.Red(fmt.Sprintf("- %s: %s", field.name, field.dc)))
+		}
+		if len(field.deployment) > 0 {
+			fmt.Fprintf(out, "%s\n", m.color.Green(fmt.Sprintf("+ %s: %s", field.name, field.deployment)))
+		}
+	}
+}
+
+// diffFields returns the compared fields of the deployment config and the deployment. The fields missing
+// on one side are empty.
+func diffFields(dc *osappsv1.DeploymentConfig, deployment *appsv1.Deployment) []diffField {
+	replicas := "unset"
+	if deployment.Spec.Replicas != nil {
+		replicas = fmt.Sprint(*deployment.Spec.Replicas)
+	}
+	fields := []diffField{
+		{name: "strategy", dc: string(dc.Spec.Strategy.Type), deployment: string(deployment.Spec.Strategy.Type)},
+		{name: "replicas", dc: fmt.Sprint(dc.Spec.Replicas), deployment: replicas},
+		// Deployments roll out on every pod template change, like the config change trigger
+		{name: "triggers", dc: deploymentConfigTriggers(dc), deployment: string(osappsv1.DeploymentTriggerOnConfigChange)},
+	}
+
+	dcContainers := []corev1.Container{}
+	if dc.Spec.Template != nil {
+		dcContainers = podContainers(&dc.Spec.Template.Spec)
+	}
+	dcImages := map[string]string{}
+	for _, container := range dcContainers {
+		dcImages[container.Name] = container.Image
+	}
+	converted := map[string]bool{}
+	for _, container := range podContainers(&deployment.Spec.Template.Spec) {
+		converted[container.Name] = true
+		fields = append(fields, diffField{name: "container " + container.Name, dc: dcImages[container.Name], deployment: container.Image})
+	}
+	for _, container := range dcContainers {
+		if !converted[container.Name] {
+			fields = append(fields, diffField{name: "container " + container.Name, dc: container.Image})
+		}
+	}
+	return fields
+}
+
+// podContainers returns the init containers and containers of the pod spec.
+func podContainers(spec *corev1.PodSpec) []corev1.Container {
+	result := append([]corev1.Container{}, spec.InitContainers...)
+	return append(result, spec.Containers...)
+}
+
+// deploymentConfigTriggers returns the description of the deployment config triggers.
+func deploymentConfigTriggers(dc *osappsv1.DeploymentConfig) string {
+	triggers := []string{}
+	for _, trigger := range dc.Spec.Triggers {
+		if trigger.Type == osappsv1.DeploymentTriggerOnImageChange && trigger.ImageChangeParams != nil {
+			from := trigger.ImageChangeParams.From
+			triggers = append(triggers, fmt.Sprintf("%s(%s %s)", trigger.Type, from.Kind, from.Name))
+			continue
+		}
+		triggers = append(triggers, string(trigger.Type))
+	}
+	if len(triggers) == 0 {
+		return "none"
+	}
+	return strings.Join(triggers, ", ")
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/logrusorgru/aurora"
+	osappsv1 "github.com/openshift/api/apps/v1"
+)
+
+func TestPrintDiff(t *testing.T) {
+	dc := newTestDeploymentConfig(1)
+	dc.Spec.Triggers = append(dc.Spec.Triggers, osappsv1.DeploymentTriggerPolicy{
+		Type: osappsv1.DeploymentTriggerOnImageChange,
+		ImageChangeParams: &osappsv1.DeploymentTriggerImageChangeParams{
+			Automatic:      true,
+			ContainerNames: []string{"web"},
+			From:           corev1.ObjectReference{Kind: "ImageStreamTag", Name: "web:latest"},
+		},
+	})
+	dc.Spec.Template.Spec.Containers = append(dc.Spec.Template.Spec.Containers, corev1.Container{Name: "proxy", Image: "envoy:1"})
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RollingUpdateDeploymentStrategyType},
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: "registry/demo/web@sha256:1"}}},
+			},
+		},
+	}
+
+	m := &MigrateOptions{color: aurora.NewAurora(false)}
+	out := &bytes.Buffer{}
+	m.printDiff(out, dc, deployment)
+	expected := `--- deploymentconfig.apps.openshift.io/demo/web
++++ deployment.apps/demo/web
+- strategy: Rolling
++ strategy: RollingUpdate
+  replicas: 3
+- triggers: ConfigChange, ImageChange(ImageStreamTag web:latest)
++ triggers: ConfigChange
+- container web: nginx:1
++ container web: registry/demo/web@sha256:1
+- container proxy: envoy:1
+`
+	if out.String() != expected {
+		t.Errorf("expected diff:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
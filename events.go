@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	osscheme "github.com/openshift/client-go/apps/clientset/versioned/scheme"
+)
+
+// eventRecorder records events on the deployment configs and deployments, so the migration is visible
+// in the object description. Unlike the client-go event broadcaster, the events are created synchronously
+// and are not lost when the command exits right after the migration.
+type eventRecorder struct {
+	client corev1client.EventsGetter
+	scheme *kruntime.Scheme
+}
+
+func newEventRecorder(client corev1client.EventsGetter) *eventRecorder {
+	eventScheme := kruntime.NewScheme()
+	scheme.AddToScheme(eventScheme)
+	osscheme.AddToScheme(eventScheme)
+	return &eventRecorder{client: client, scheme: eventScheme}
+}
+
+// Event records the normal event with the given reason and message on the object.
+func (r *eventRecorder) Event(obj kruntime.Object, reason, message string) error {
+	ref, err := r.reference(obj)
+	if err != nil {
+		return err
+	}
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", ref.Name, now.UnixNano()),
+			Namespace: ref.Namespace,
+		},
+		InvolvedObject: *ref,
+		Reason:         reason,
+		Message:        message,
+		Type:           corev1.EventTypeNormal,
+		Source:         corev1.EventSource{Component: "migrate-to-deployment"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	_, err = r.client.Events(ref.Namespace).Create(event)
+	return err
+}
+
+// reference returns the reference to the object. The kind and API version come from the scheme, the typed
+// clients drop them and the servers no longer set the self link reference.GetReference() falls back to.
+func (r *eventRecorder) reference(obj kruntime.Object) (*corev1.ObjectReference, error) {
+	gvks, _, err := r.scheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, err
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.ObjectReference{
+		Kind:            gvks[0].Kind,
+		APIVersion:      gvks[0].GroupVersion().String(),
+		Name:            accessor.GetName(),
+		Namespace:       accessor.GetNamespace(),
+		UID:             accessor.GetUID(),
+		ResourceVersion: accessor.GetResourceVersion(),
+	}, nil
+}
+
+// event records the event on the object. The events only document the migration, failures are reported
+// as warnings and the migration continues.
+func (m *MigrateOptions) event(key string, obj kruntime.Object, reason, message string) {
+	if m.recorder == nil || m.DryRun {
+		return
+	}
+	if err := m.recorder.Event(obj, reason, message); err != nil {
+		m.warning("event", key, fmt.Sprintf("unable to record %q event: %v", reason, err))
+	}
+}
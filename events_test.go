@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMigrateEvents(t *testing.T) {
+	dc := newTestDeploymentConfig(2)
+	m, _ := newTestOptions(t, dc, newTestReplicationController(dc, 1, 0), newTestReplicationController(dc, 2, 3))
+	m.recorder = newEventRecorder(m.CoreClient)
+
+	if err := m.migrate("demo/web"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	events, err := m.CoreClient.Events("demo").List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reasons := map[string][]string{}
+	for _, event := range events.Items {
+		object := event.InvolvedObject.Kind + "/" + event.InvolvedObject.Name
+		reasons[object] = append(reasons[object], event.Reason)
+		if event.Source.Component != "migrate-to-deployment" {
+			t.Errorf("expected the migrate-to-deployment event source, got %q", event.Source.Component)
+		}
+	}
+	expected := map[string][]string{
+		"DeploymentConfig/web": {"Converted", "Paused", "DeploymentCreated"},
+		"Deployment/web":       {"Migrated", "HistoryMigrated"},
+	}
+	if !reflect.DeepEqual(reasons, expected) {
+		t.Errorf("expected event reasons %v, got %v", expected, reasons)
+	}
+}
+
+func TestMigrateEventsDryRun(t *testing.T) {
+	m, fake := newTestOptions(t, newTestDeploymentConfig(1))
+	m.recorder = newEventRecorder(m.CoreClient)
+	m.DryRun = true
+	m.OutputFormat = "yaml"
+
+	if err := m.migrate("demo/web"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, action := range fake.Actions() {
+		if action.GetResource().Resource == "events" {
+			t.Errorf("expected no events in dry run, got %v", action)
+		}
+	}
+}
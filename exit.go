@@ -0,0 +1,50 @@
+package main
+
+import (
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// Exit codes of the command, so scripts can tell the failures apart.
+const (
+	// exitCodeError is used for any error not covered by the other exit codes.
+	exitCodeError = 1
+	// exitCodeValidation is used when the arguments or flags are invalid.
+	exitCodeValidation = 2
+	// exitCodePartialFailure is used when some deployment configs of a batch failed to migrate.
+	exitCodePartialFailure = 3
+	// exitCodeRolledBack is used when the migration failed and its changes were rolled back.
+	exitCodeRolledBack = 4
+)
+
+// validationError is returned when the arguments or flags are invalid.
+type validationError struct {
+	error
+}
+
+// partialFailureError is returned when deployment configs of a batch failed to migrate and the
+// migration continued with the rest of the batch.
+type partialFailureError struct {
+	utilerrors.Aggregate
+}
+
+// rolledBackError is returned when the migration failed and the objects created or changed by the migration
+// were reverted, so the deployment config keeps running the application.
+type rolledBackError struct {
+	error
+}
+
+// exitCode returns the exit code for the error returned by the command.
+func exitCode(err error) int {
+	switch err.(type) {
+	case nil:
+		return 0
+	case *validationError:
+		return exitCodeValidation
+	case *partialFailureError:
+		return exitCodePartialFailure
+	case *rolledBackError:
+		return exitCodeRolledBack
+	default:
+		return exitCodeError
+	}
+}
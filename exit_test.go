@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "success", expected: 0},
+		{name: "error", err: fmt.Errorf("failed"), expected: exitCodeError},
+		{name: "validation", err: &validationError{fmt.Errorf("invalid")}, expected: exitCodeValidation},
+		{name: "partial failure", err: &partialFailureError{utilerrors.NewAggregate([]error{fmt.Errorf("failed")})}, expected: exitCodePartialFailure},
+		{name: "rolled back", err: &rolledBackError{fmt.Errorf("failed")}, expected: exitCodeRolledBack},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if code := exitCode(test.err); code != test.expected {
+				t.Errorf("expected exit code %d, got %d", test.expected, code)
+			}
+		})
+	}
+}
+
+func TestRunExitCode(t *testing.T) {
+	broken := newTestDeploymentConfig(1)
+	broken.Name = "broken"
+	broken.Spec.Strategy.Type = "Unknown"
+
+	tests := []struct {
+		name string
+		keys []string
+		// createErr fails the creation of the deployments after the deployment config is paused.
+		createErr        error
+		expectedExitCode int
+	}{
+		{
+			name: "migrated",
+			keys: []string{"demo/web"},
+		},
+		{
+			name:             "failure before pausing",
+			keys:             []string{"demo/broken"},
+			expectedExitCode: exitCodeError,
+		},
+		{
+			name:             "failure after pausing is rolled back",
+			keys:             []string{"demo/web"},
+			createErr:        fmt.Errorf("connection refused"),
+			expectedExitCode: exitCodeRolledBack,
+		},
+		{
+			name:             "partial batch failure",
+			keys:             []string{"demo/broken", "demo/web"},
+			expectedExitCode: exitCodePartialFailure,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, fake := newTestOptions(t, broken.DeepCopy(), newTestDeploymentConfig(1))
+			m.DeploymentConfigKeys = test.keys
+			if test.createErr != nil {
+				fake.PrependReactor("create", "deployments", func(action clienttesting.Action) (bool, kruntime.Object, error) {
+					return true, nil, test.createErr
+				})
+			}
+			err := m.Run()
+			if code := exitCode(err); code != test.expectedExitCode {
+				t.Errorf("expected exit code %d, got %d (%v)", test.expectedExitCode, code, err)
+			}
+		})
+	}
+}
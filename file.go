@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	osappsv1 "github.com/openshift/api/apps/v1"
+	"github.com/openshift/client-go/apps/clientset/versioned/scheme"
+)
+
+// readDeploymentConfigs decodes the deployment configs from the given YAML or JSON manifest files.
+// Every file can hold multiple manifests separated by "---".
+func readDeploymentConfigs(paths []string) ([]*osappsv1.DeploymentConfig, error) {
+	result := []*osappsv1.DeploymentConfig{}
+	decoder := scheme.Codecs.UniversalDeserializer()
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		reader := yaml.NewYAMLReader(bufio.NewReader(f))
+		for {
+			data, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				f.Close()
+				return nil, fmt.Errorf("unable to read %q: %v", path, err)
+			}
+			if len(data) == 0 {
+				continue
+			}
+			dcs, err := decodeDeploymentConfigs(decoder, data)
+			if err != nil {
+				f.Close()
+				return nil, fmt.Errorf("unable to decode %q: %v", path, err)
+			}
+			result = append(result, dcs...)
+		}
+		f.Close()
+	}
+	return result, nil
+}
+
+// decodeDeploymentConfigs decodes the manifest holding a deployment config, a deployment config list or
+// a List of deployment configs as printed by "oc get dc -o yaml".
+func decodeDeploymentConfigs(decoder kruntime.Decoder, data []byte) ([]*osappsv1.DeploymentConfig, error) {
+	data, err := yaml.ToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	list := struct {
+		Kind  string            `json:"kind"`
+		Items []json.RawMessage `json:"items"`
+	}{}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	if list.Kind == "List" {
+		result := []*osappsv1.DeploymentConfig{}
+		for _, item := range list.Items {
+			dcs, err := decodeDeploymentConfigs(decoder, item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, dcs...)
+		}
+		return result, nil
+	}
+
+	obj, _, err := decoder.Decode(data, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch obj := obj.(type) {
+	case *osappsv1.DeploymentConfig:
+		return []*osappsv1.DeploymentConfig{obj}, nil
+	case *osappsv1.DeploymentConfigList:
+		result := []*osappsv1.DeploymentConfig{}
+		for i := range obj.Items {
+			result = append(result, &obj.Items[i])
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("unexpected object %T, only deployment configs are supported", obj)
+}
+
+// readDeploymentConfigNames reads the deployment config names from the file, which is either a YAML list
+// or has a name per line. The names have the same [namespace/][dc/]name form as the arguments, the empty
+// lines and the lines starting with # are ignored. The manifests of deployment configs, as printed by
+// "oc get dc -o yaml", are accepted too and the names of the deployment configs in them are returned.
+func readDeploymentConfigNames(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if isManifest(data) {
+		dcs, err := readDeploymentConfigs([]string{path})
+		if err != nil {
+			return nil, err
+		}
+		names := []string{}
+		for _, dc := range dcs {
+			names = append(names, deploymentConfigKey(dc.Namespace, dc.Name))
+		}
+		return names, nil
+	}
+	names := []string{}
+	if jsonData, err := yaml.ToJSON(data); err == nil && json.Unmarshal(jsonData, &names) == nil {
+		return names, nil
+	}
+	names = []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names, nil
+}
+
+// isManifest returns true when the first document of the YAML or JSON data is an object with a kind.
+func isManifest(data []byte) bool {
+	reader := yaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	document, err := reader.Read()
+	for err == nil && len(bytes.TrimSpace(document)) == 0 {
+		document, err = reader.Read()
+	}
+	if err != nil {
+		return false
+	}
+	jsonData, err := yaml.ToJSON(document)
+	if err != nil {
+		return false
+	}
+	object := struct {
+		Kind string `json:"kind"`
+	}{}
+	return json.Unmarshal(jsonData, &object) == nil && len(object.Kind) > 0
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/logrusorgru/aurora"
+
+	"github.com/mfojtik/migrate-to-deployment/pkg/converter"
+)
+
+// writeManifest writes the manifest into the temporary directory and returns its path.
+func writeManifest(t *testing.T, name, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestReadDeploymentConfigs(t *testing.T) {
+	tests := []struct {
+		name          string
+		manifests     []string
+		expectedNames []string
+		expectedErr   string
+	}{
+		{
+			name: "multiple documents",
+			manifests: []string{`apiVersion: apps.openshift.io/v1
+kind: DeploymentConfig
+metadata:
+  name: web
+  namespace: demo
+spec:
+  replicas: 2
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx:1
+---
+---
+apiVersion: apps.openshift.io/v1
+kind: DeploymentConfig
+metadata:
+  name: db
+`},
+			expectedNames: []string{"demo/web", "/db"},
+		},
+		{
+			name: "multiple files",
+			manifests: []string{
+				`{"apiVersion": "apps.openshift.io/v1", "kind": "DeploymentConfig", "metadata": {"name": "web"}}`,
+				`{"apiVersion": "apps.openshift.io/v1", "kind": "DeploymentConfig", "metadata": {"name": "db"}}`,
+			},
+			expectedNames: []string{"/web", "/db"},
+		},
+		{
+			name: "list",
+			manifests: []string{`apiVersion: v1
+kind: List
+items:
+- apiVersion: apps.openshift.io/v1
+  kind: DeploymentConfig
+  metadata:
+    name: web
+    namespace: demo
+- apiVersion: apps.openshift.io/v1
+  kind: DeploymentConfig
+  metadata:
+    name: db
+    namespace: demo
+`},
+			expectedNames: []string{"demo/web", "demo/db"},
+		},
+		{
+			name: "deployment config list",
+			manifests: []string{`apiVersion: apps.openshift.io/v1
+kind: DeploymentConfigList
+items:
+- metadata:
+    name: web
+    namespace: demo
+`},
+			expectedNames: []string{"demo/web"},
+		},
+		{
+			name: "other kind",
+			manifests: []string{`apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`},
+			expectedErr: `no kind "Service" is registered`,
+		},
+		{
+			name: "other apps kind in list",
+			manifests: []string{`apiVersion: v1
+kind: List
+items:
+- apiVersion: apps.openshift.io/v1
+  kind: DeploymentRequest
+  name: web
+`},
+			expectedErr: "unexpected object *v1.DeploymentRequest, only deployment configs are supported",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			paths := []string{}
+			for _, manifest := range test.manifests {
+				paths = append(paths, writeManifest(t, "dc.yaml", manifest))
+			}
+			dcs, err := readDeploymentConfigs(paths)
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			names := []string{}
+			for _, dc := range dcs {
+				names = append(names, dc.Namespace+"/"+dc.Name)
+			}
+			if !reflect.DeepEqual(names, test.expectedNames) {
+				t.Errorf("expected deployment configs %v, got %v", test.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestReadDeploymentConfigNames(t *testing.T) {
+	tests := []struct {
+		name          string
+		contents      string
+		expectedNames []string
+		expectedErr   string
+	}{
+		{
+			name:          "name per line",
+			contents:      "# frontend\nweb\n\n  shop/dc/db  \n",
+			expectedNames: []string{"web", "shop/dc/db"},
+		},
+		{
+			name:          "yaml list",
+			contents:      "- web\n- shop/db\n",
+			expectedNames: []string{"web", "shop/db"},
+		},
+		{
+			name: "multiple documents",
+			contents: `---
+apiVersion: apps.openshift.io/v1
+kind: DeploymentConfig
+metadata:
+  name: web
+  namespace: shop
+---
+apiVersion: apps.openshift.io/v1
+kind: DeploymentConfig
+metadata:
+  name: db
+`,
+			expectedNames: []string{"shop/web", "db"},
+		},
+		{
+			name: "list",
+			contents: `{"apiVersion": "v1", "kind": "List", "items": [
+  {"apiVersion": "apps.openshift.io/v1", "kind": "DeploymentConfig", "metadata": {"name": "web", "namespace": "shop"}}]}`,
+			expectedNames: []string{"shop/web"},
+		},
+		{
+			name: "other kind",
+			contents: `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`,
+			expectedErr: `no kind "Service" is registered`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names, err := readDeploymentConfigNames(writeManifest(t, "names", test.contents))
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(names, test.expectedNames) {
+				t.Errorf("expected names %v, got %v", test.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestRunFilename(t *testing.T) {
+	api := newTestDeploymentConfig(1)
+	api.Name = "api"
+	m, _ := newTestOptions(t, api, newTestDeploymentConfig(1))
+	m.Filename = writeManifest(t, "names", "web\napi\n")
+	m.ServiceFixup, m.ReportFormat, m.LogFormat, m.Apply = "label", "text", "text", "create"
+	m.ConverterOptions.SelectorLabel = "app"
+	m.ConverterOptions.ImageResolution = converter.ImageResolutionLastTriggered
+
+	cmd := NewMigrateCommand(&bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{})
+	if err := m.Validate(cmd, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"web", "api"}; !reflect.DeepEqual(m.DeploymentConfigKeys, expected) {
+		t.Fatalf("expected deployment configs %v, got %v", expected, m.DeploymentConfigKeys)
+	}
+	if err := m.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"web", "api"} {
+		if _, err := m.AppsClient.Deployments("demo").Get(name, metav1.GetOptions{}); err != nil {
+			t.Errorf("expected deployment %q to be migrated: %v", name, err)
+		}
+	}
+}
+
+func TestRunFromFile(t *testing.T) {
+	path := writeManifest(t, "web.yaml", `apiVersion: apps.openshift.io/v1
+kind: DeploymentConfig
+metadata:
+  name: web
+spec:
+  replicas: 2
+  strategy:
+    type: Rolling
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx:1
+`)
+	m := &MigrateOptions{
+		Output:       &bytes.Buffer{},
+		ErrOut:       &bytes.Buffer{},
+		Namespace:    "demo",
+		FromFiles:    []string{path},
+		OutputFormat: "yaml",
+		ServiceFixup: "label",
+		ReportFormat: "text",
+		LogFormat:    "text",
+		Parallelism:  1,
+		Apply:        "create",
+		color:        aurora.NewAurora(false),
+		convert:      converter.Convert,
+
+		ConverterOptions: converter.Options{SelectorLabel: "app", ImageResolution: converter.ImageResolutionLastTriggered},
+	}
+	cmd := NewMigrateCommand(&bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{})
+	if err := m.Validate(cmd, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.Complete(cmd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(m.Output.(*bytes.Buffer).Bytes(), nil, nil)
+	if err != nil {
+		t.Fatalf("unable to decode the output: %v", err)
+	}
+	deployment, ok := obj.(*appsv1.Deployment)
+	if !ok {
+		t.Fatalf("expected deployment, got %T", obj)
+	}
+	if deployment.Namespace != "demo" || deployment.Name != "web" || *deployment.Spec.Replicas != 2 {
+		t.Errorf("expected demo/web deployment with 2 replicas, got %s/%s with %d", deployment.Namespace, deployment.Name, *deployment.Spec.Replicas)
+	}
+	if deployment.Spec.Template.Spec.Containers[0].Image != "nginx:1" || deployment.Spec.Selector.MatchLabels["app"] != "web" {
+		t.Errorf("expected the deployment config pod template, got %#v", deployment.Spec)
+	}
+}
+
+func TestNonTerminalOutputHasNoColors(t *testing.T) {
+	path := writeManifest(t, "web.yaml", `{"apiVersion": "apps.openshift.io/v1", "kind": "DeploymentConfig", "metadata": {"name": "web"},
+"spec": {"strategy": {"type": "Rolling"}, "template": {"spec": {"containers": [{"name": "web", "image": "nginx:1"}]}}}}`)
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := NewMigrateCommand(&bytes.Buffer{}, out, errOut)
+	cmd.SetArgs([]string{"--from-file", path, "-n", "demo"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output := out.String() + errOut.String(); !strings.Contains(output, "converting deployment config") || strings.Contains(output, "\x1b[") {
+		t.Errorf("expected the progress without color sequences, got %q", output)
+	}
+}
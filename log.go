@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// logRecord is a single progress event printed with the json log format.
+type logRecord struct {
+	Level            string `json:"level"`
+	Event            string `json:"event"`
+	Namespace        string `json:"namespace,omitempty"`
+	DeploymentConfig string `json:"dc,omitempty"`
+	Step             int    `json:"step,omitempty"`
+	Message          string `json:"message"`
+}
+
+// stepCounter counts the progress steps of the migration of a single deployment config.
+type stepCounter struct {
+	// index is the position of the deployment config among all migrated deployment configs.
+	index   int
+	total   int
+	step    int
+	started time.Time
+}
+
+// colorSequence matches the terminal color escape sequences, which are stripped from structured records.
+var colorSequence = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// isTerminal returns true when the input or output stream is a terminal. It is a variable, so the tests can
+// simulate a terminal.
+var isTerminal = func(stream interface{}) bool {
+	f, ok := stream.(*os.File)
+	return ok && terminal.IsTerminal(int(f.Fd()))
+}
+
+// logOutput returns the writer for the progress messages.
+func (m *MigrateOptions) logOutput() io.Writer {
+	// The output is reserved for the printed objects and results
+	if len(m.OutputFormat) > 0 || len(m.ResultFormat) > 0 {
+		return m.ErrOut
+	}
+	return m.Output
+}
+
+// progress reports the migration event for the deployment config with the given namespace/name key.
+// In quiet mode, only the summaries are reported.
+func (m *MigrateOptions) progress(event, key, message string) {
+	if m.Quiet && event != "finished" && event != "summary" {
+		return
+	}
+	m.log(m.logOutput(), "info", event, key, message)
+}
+
+func (m *MigrateOptions) warning(event, key, message string) {
+	m.log(m.logOutput(), "warning", event, key, m.color.Brown("WARNING:").String()+" "+message)
+}
+
+func (m *MigrateOptions) error(event, key string, err error) {
+	m.log(m.ErrOut, "error", event, key, m.color.Red("ERROR:").String()+" "+err.Error())
+}
+
+func (m *MigrateOptions) log(out io.Writer, level, event, key, message string) {
+	step := 0
+	if m.steps != nil && level != "error" {
+		m.steps.step++
+		step = m.steps.step
+	}
+
+	if m.LogFormat != "json" {
+		if level == "error" {
+			fmt.Fprintln(out, message)
+			return
+		}
+		prefix := m.color.Bold("-->").String()
+		if step > 0 {
+			prefix += " " + m.color.Gray(fmt.Sprintf("[%d/%d step %d]", m.steps.index, m.steps.total, step)).String()
+		}
+		fmt.Fprintln(out, prefix+" "+message)
+		return
+	}
+
+	namespace, name := splitDeploymentConfigKey(key)
+	if len(namespace) == 0 {
+		namespace = m.Namespace
+	}
+	record := logRecord{
+		Level:            level,
+		Event:            event,
+		Namespace:        namespace,
+		DeploymentConfig: name,
+		Step:             step,
+		Message:          colorSequence.ReplaceAllString(message, ""),
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		fmt.Fprintf(m.ErrOut, "unable to encode log record: %v\n", err)
+		return
+	}
+	fmt.Fprintln(out, string(data))
+}
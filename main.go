@@ -1,31 +1,67 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
 	goflag "flag"
 	"fmt"
 	"io"
 	"math/rand"
+	"net/http"
 	"os"
-	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
 
-	color "github.com/logrusorgru/aurora"
+	"github.com/logrusorgru/aurora"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kversion "k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes/scheme"
 	appsv1client "k8s.io/client-go/kubernetes/typed/apps/v1"
+	autoscalingv1client "k8s.io/client-go/kubernetes/typed/autoscaling/v1"
+	batchv1client "k8s.io/client-go/kubernetes/typed/batch/v1"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	policyv1beta1client "k8s.io/client-go/kubernetes/typed/policy/v1beta1"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/retry"
 
 	osappsv1 "github.com/openshift/api/apps/v1"
 	osappsv1client "github.com/openshift/client-go/apps/clientset/versioned/typed/apps/v1"
+	imagev1client "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
 	"github.com/openshift/library-go/pkg/serviceability"
+
+	"github.com/mfojtik/migrate-to-deployment/pkg/converter"
 )
 
+// pollInterval is the interval of polling the deployment and lifecycle hook job status while waiting.
+var pollInterval = 2 * time.Second
+
+// version is the version of the migration tool, set at build time using -ldflags "-X main.version=...".
+var version = "unknown"
+
 func main() {
 	rand.Seed(time.Now().UTC().UnixNano())
 	pflag.CommandLine.AddGoFlagSet(goflag.CommandLine)
@@ -35,43 +71,413 @@ func main() {
 		runtime.GOMAXPROCS(runtime.NumCPU())
 	}
 
-	command := NewMigrateCommand(os.Stdout)
+	command := NewMigrateCommand(os.Stdin, os.Stdout, os.Stderr)
+	// The commands print their errors, the exit code tells them apart
 	if err := command.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
 type MigrateOptions struct {
+	Input  io.Reader
 	Output io.Writer
+	ErrOut io.Writer
 
-	DeploymentConfigNames []string
-	Namespace             string
+	// DeploymentConfigKeys are the deployment configs to migrate in namespace/name form.
+	DeploymentConfigKeys []string
+	// Namespace is the namespace of the deployment configs given without namespace.
+	Namespace string
+	// Filename is the file with the names of the deployment configs to migrate, in addition to the arguments.
+	Filename string
+	// FromFiles are the manifest files to read the deployment configs from instead of the cluster.
+	FromFiles []string
+	// All migrates all deployment configs in the namespace.
+	All bool
+	// AllNamespaces migrates the deployment configs in all namespaces.
+	AllNamespaces bool
+	// Selector is the label selector used to list the deployment configs to migrate.
+	Selector string
+	// Yes skips the confirmation prompt before mutating the cluster objects.
+	Yes bool
+	// FailFast stops the migration of multiple deployment configs on the first failure.
+	FailFast bool
+	// Parallelism is the number of deployment configs migrated concurrently.
+	Parallelism int
 
 	OsAppsClient osappsv1client.AppsV1Interface
 	AppsClient   appsv1client.AppsV1Interface
 	CoreClient   corev1client.CoreV1Interface
+	BatchClient  batchv1client.BatchV1Interface
+	ImageClient  imagev1client.ImageV1Interface
+
+	AutoscalingClient autoscalingv1client.AutoscalingV1Interface
+	PolicyClient      policyv1beta1client.PolicyV1beta1Interface
+
+	ConverterOptions converter.Options
+
+	// DryRun only prints the converted deployments and does not mutate any cluster objects.
+	DryRun bool
+	// Diff prints the differences between the deployment configs and the converted deployments, it implies DryRun.
+	Diff bool
+	// Hooks controls how the deployment config lifecycle hooks are converted (jobs), by default they are dropped.
+	Hooks string
+	// MigratePDB recreates the pod disruption budgets selecting the deployment config pods to select the deployment pods.
+	MigratePDB bool
+	// MigrateHPA repoints the horizontal pod autoscalers scaling the deployment config to the deployment.
+	MigrateHPA bool
+	// ServiceFixup controls how the services selecting the deployment config pods are fixed up (label or selector).
+	ServiceFixup string
+	// Apply controls how the deployment is created: create (or replace) it, or use server-side apply (ssa).
+	Apply string
+	// FieldManager is the field manager the created and updated objects are attributed to.
+	FieldManager string
+	// ValidateOnServer validates the deployment using server-side dry run before the deployment config is paused.
+	ValidateOnServer bool
+	// KeepPaused leaves the migrated deployment paused.
+	KeepPaused bool
+	// PauseManualRollouts leaves the deployments converted from manually rolled out deployment configs paused.
+	PauseManualRollouts bool
+	// Wait waits for the deployment to become available.
+	Wait bool
+	// Prune deletes the deployment config once the deployment is available.
+	Prune bool
+	// Timeout is the deadline of the migration of a deployment config, including the waits for the deployment
+	// and the lifecycle hook jobs. The migration is rolled back when the deadline expires.
+	Timeout time.Duration
+	// QPS is the maximum number of API requests per second the clients make.
+	QPS float32
+	// Burst is the maximum number of API requests the clients make at once above the QPS.
+	Burst int
+	// ReMigrate migrates the deployment configs that were already migrated again, replacing their deployments.
+	ReMigrate bool
+	// VerifyImageExists checks that the container images of the converted deployments are well-formed image pull specs.
+	VerifyImageExists bool
+	// PreserveReplicasFromRC uses the replicas of the replication controller of the latest complete rollout
+	// instead of the deployment config replicas.
+	PreserveReplicasFromRC bool
+	// MaxReplicas, when set, generates a horizontal pod autoscaler scaling the deployment up to these replicas.
+	MaxReplicas int32
+	// MinReplicas and CPUTarget are the min replicas and target CPU utilization of the generated horizontal pod
+	// autoscaler, zero keeps the Kubernetes defaults.
+	MinReplicas int32
+	CPUTarget   int32
+	// RequireHistory fails the migration when the replication controllers cannot be listed because of missing
+	// permissions, instead of migrating without the rollout history.
+	RequireHistory bool
+	// SkipHistory skips the migration of the replication controllers, the deployment starts without rollout history.
+	SkipHistory bool
+	// Overwrite replaces existing deployments instead of failing.
+	Overwrite bool
+	// ReportFormat is the format of the migration report printed for every deployment config (text or json).
+	ReportFormat string
+	// OutputFormat is the format used to print the resulting objects (yaml or json). The name format prints
+	// only the object names, the kustomize format writes the kustomization.yaml listing the objects written
+	// into the output directory instead.
+	OutputFormat string
+	// OutputDir is the directory the resulting objects are written into as separate YAML files.
+	OutputDir string
+	// StripClusterFields removes the cluster specific fields from the written and printed objects.
+	StripClusterFields bool
+	// LogFormat is the format of the progress messages (text or json).
+	LogFormat string
+	// ResultFormat is the format of the migration results printed after all deployment configs were processed.
+	// Only json is supported, the results are not printed when not set.
+	ResultFormat string
+	// Quiet suppresses the progress messages except the summaries, the warnings and errors are still printed.
+	Quiet bool
+	// NoColor disables the colored output. The colors are disabled automatically when the output is not a terminal.
+	NoColor bool
+
+	fileDeploymentConfigs map[string]*osappsv1.DeploymentConfig
+
+	color aurora.Aurora
+	steps *stepCounter
+	// interactive is true when the input is a terminal the confirmation can be read from.
+	interactive bool
+	// replicas is the value of the --replicas flag, which is only used when the flag is set.
+	replicas int
+	// written are the object files written into the output directory.
+	written *writtenFiles
+	// results are the results of the migrated deployment configs, indexed the same way as the keys.
+	results []migrationResult
+	// result is the result of the deployment config being migrated.
+	result *migrationResult
+	// recorder records the migration events, it is not set when the deployment configs are read from files.
+	recorder *eventRecorder
+	// restConfig is the client configuration the clients of every migration are created from.
+	restConfig *rest.Config
+	// ctx is the context of the migration of a deployment config, the waits are stopped and the API requests
+	// are canceled once it is done.
+	ctx context.Context
 
-	kubeconfig string
+	maxSurge       string
+	maxUnavailable string
 
-	convert        func(*osappsv1.DeploymentConfig, *appsv1.Deployment) error
-	migrateHistory func(*appsv1.Deployment, []corev1.ReplicationController) error
+	configFile        string
+	kubeconfig        string
+	context           string
+	impersonate       string
+	impersonateGroups []string
+
+	convert        func(*osappsv1.DeploymentConfig, converter.Options) (*appsv1.Deployment, error)
+	migrateHistory func(*appsv1.Deployment, []corev1.ReplicationController) ([]*appsv1.ReplicaSet, error)
+	// loadClients creates the clients of the verify and undo commands.
+	loadClients func() error
+}
+
+// completeStreams applies the config file and sets up the colors and the interactive mode from the command
+// streams. The config file is applied first, so its values are honored by the colors and the validation.
+func (m *MigrateOptions) completeStreams(c *cobra.Command) error {
+	if len(m.configFile) > 0 {
+		if err := applyConfigFile(c.Flags(), m.configFile); err != nil {
+			return err
+		}
+	}
+	m.color = aurora.NewAurora(!m.NoColor && isTerminal(m.Output) && isTerminal(m.ErrOut))
+	m.interactive = isTerminal(m.Input)
+	return nil
 }
 
-func (m *MigrateOptions) Validate(c *cobra.Command) error {
-	if len(os.Args) == 1 {
-		return fmt.Errorf("deployment config name(s) must be specified\n")
+func (m *MigrateOptions) Validate(c *cobra.Command, args []string) error {
+	if len(m.Selector) > 0 {
+		if _, err := labels.Parse(m.Selector); err != nil {
+			return fmt.Errorf("invalid label selector %q: %v", m.Selector, err)
+		}
+	}
+	if len(m.Filename) > 0 {
+		names, err := readDeploymentConfigNames(m.Filename)
+		if err != nil {
+			return err
+		}
+		args = append(args, names...)
+	}
+	if len(args) == 0 && !m.listDeploymentConfigs() && !m.offline() {
+		return fmt.Errorf("deployment config name(s) must be specified")
+	}
+	m.DeploymentConfigKeys = []string{}
+	if m.listDeploymentConfigs() || m.offline() {
+		// The deployment configs are listed from the namespace or read from files in Complete()
+		args = nil
+	}
+	if m.AllNamespaces && m.offline() {
+		return fmt.Errorf("--all-namespaces cannot be used with --from-file")
+	}
+	if m.offline() || m.Diff {
+		// Deployment configs read from files are never migrated in the cluster
+		m.DryRun = true
+	}
+	if err := m.parseDeploymentConfigArgs(args); err != nil {
+		return err
+	}
+	switch m.Hooks {
+	case "", "jobs", "init":
+	default:
+		return fmt.Errorf("unsupported hooks mode %q, must be one of: jobs, init", m.Hooks)
+	}
+	switch m.ServiceFixup {
+	case "label", "selector":
+	default:
+		return fmt.Errorf("unsupported service fixup %q, must be one of: label, selector", m.ServiceFixup)
+	}
+	if m.PreserveReplicasFromRC && (c.Flags().Changed("replicas") || m.offline()) {
+		return fmt.Errorf("--preserve-replicas-from-rc cannot be used with --replicas or --from-file")
+	}
+	if c.Flags().Changed("replicas") {
+		if m.replicas < 0 {
+			return fmt.Errorf("--replicas must not be negative")
+		}
+		replicas := int32(m.replicas)
+		m.ConverterOptions.Replicas = &replicas
+	}
+	if errs := validation.IsQualifiedName(m.ConverterOptions.SelectorLabel); len(errs) > 0 {
+		return fmt.Errorf("invalid selector label %q: %s", m.ConverterOptions.SelectorLabel, strings.Join(errs, "; "))
+	}
+	if len(m.maxSurge) > 0 {
+		maxSurge, err := parseIntOrPercent(m.maxSurge)
+		if err != nil {
+			return fmt.Errorf("invalid --max-surge: %v", err)
+		}
+		m.ConverterOptions.MaxSurge = &maxSurge
+	}
+	if len(m.maxUnavailable) > 0 {
+		maxUnavailable, err := parseIntOrPercent(m.maxUnavailable)
+		if err != nil {
+			return fmt.Errorf("invalid --max-unavailable: %v", err)
+		}
+		m.ConverterOptions.MaxUnavailable = &maxUnavailable
+	}
+	if surge, unavailable := m.ConverterOptions.MaxSurge, m.ConverterOptions.MaxUnavailable; surge != nil && unavailable != nil && isZeroIntOrPercent(*surge) && isZeroIntOrPercent(*unavailable) {
+		return fmt.Errorf("--max-surge and --max-unavailable must not both be zero, the rollout could not make progress")
+	}
+	if m.Parallelism < 1 {
+		return fmt.Errorf("--parallelism must be at least 1")
+	}
+	if m.Prune && !m.Wait {
+		return fmt.Errorf("--prune requires --wait, the deployment config is only deleted when the deployment is available")
+	}
+	if m.Wait && m.KeepPaused {
+		return fmt.Errorf("--wait cannot be used with --keep-paused")
+	}
+	if m.MaxReplicas < 0 || m.MinReplicas < 0 || m.CPUTarget < 0 {
+		return fmt.Errorf("--max-replicas, --min-replicas and --cpu-target must not be negative")
+	}
+	if m.MaxReplicas == 0 && (m.MinReplicas > 0 || m.CPUTarget > 0) {
+		return fmt.Errorf("--min-replicas and --cpu-target require --max-replicas")
+	}
+	if m.MinReplicas > m.MaxReplicas {
+		return fmt.Errorf("--min-replicas must not be greater than --max-replicas")
+	}
+	if m.RequireHistory && m.SkipHistory {
+		return fmt.Errorf("--require-history cannot be used with --skip-history")
+	}
+	switch m.ReportFormat {
+	case "text", "json":
+	default:
+		return fmt.Errorf("unsupported report format %q, must be one of: text, json", m.ReportFormat)
+	}
+	switch m.Apply {
+	case "create":
+	case "ssa":
+		if len(m.FieldManager) == 0 {
+			return fmt.Errorf("--apply=ssa requires --field-manager")
+		}
+	default:
+		return fmt.Errorf("unsupported apply mode %q, must be one of: create, ssa", m.Apply)
+	}
+	switch m.ConverterOptions.ImageResolution {
+	case converter.ImageResolutionLastTriggered, converter.ImageResolutionImageStream, converter.ImageResolutionKeep:
+	default:
+		return fmt.Errorf("unsupported image resolution %q, must be one of: %s, %s, %s", m.ConverterOptions.ImageResolution,
+			converter.ImageResolutionLastTriggered, converter.ImageResolutionImageStream, converter.ImageResolutionKeep)
+	}
+	switch m.ConverterOptions.ImageAutomation {
+	case "", converter.ImageAutomationNone, converter.ImageAutomationArgoCD:
+	default:
+		return fmt.Errorf("unsupported image automation %q, must be one of: %s, %s", m.ConverterOptions.ImageAutomation,
+			converter.ImageAutomationArgoCD, converter.ImageAutomationNone)
+	}
+	switch m.ResultFormat {
+	case "", "json":
+	default:
+		return fmt.Errorf("unsupported result format %q, must be: json", m.ResultFormat)
+	}
+	switch m.LogFormat {
+	case "text", "json":
+	default:
+		return fmt.Errorf("unsupported log format %q, must be one of: text, json", m.LogFormat)
+	}
+	switch m.OutputFormat {
+	case "", "yaml", "json":
+	case "name":
+		// Scripts capture the names, the progress messages are reduced to the summaries
+		m.Quiet = true
+	case "kustomize":
+		if len(m.OutputDir) == 0 {
+			return fmt.Errorf("--output=kustomize requires --output-dir")
+		}
+	default:
+		return fmt.Errorf("unsupported output format %q, must be one of: yaml, json, name, kustomize", m.OutputFormat)
+	}
+	// Without the output directory, the dry run objects are printed unless the diff or results are printed instead
+	if m.DryRun && !m.Diff && len(m.ResultFormat) == 0 && len(m.OutputFormat) == 0 && len(m.OutputDir) == 0 {
+		m.OutputFormat = "yaml"
 	}
-	m.DeploymentConfigNames = os.Args[1:]
 	return nil
 }
 
+// clientConfig returns the client configuration loaded from the kubeconfig, using the selected context.
+// The default loading rules honor $KUBECONFIG and ~/.kube/config, when no kubeconfig is found
+// the in-cluster configuration is used, so the migration can run as a job inside the cluster.
+func (m *MigrateOptions) clientConfig() clientcmd.ClientConfig {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = m.kubeconfig
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{
+		CurrentContext: m.context,
+	})
+}
+
 func (m *MigrateOptions) Complete(c *cobra.Command) error {
-	config, err := clientcmd.BuildConfigFromFlags("", m.kubeconfig)
+	if m.offline() {
+		dcs, err := readDeploymentConfigs(m.FromFiles)
+		if err != nil {
+			return err
+		}
+		m.fileDeploymentConfigs = map[string]*osappsv1.DeploymentConfig{}
+		for _, dc := range dcs {
+			if len(dc.Namespace) == 0 {
+				dc.Namespace = m.Namespace
+			}
+			key := deploymentConfigKey(dc.Namespace, dc.Name)
+			m.fileDeploymentConfigs[key] = dc
+			m.DeploymentConfigKeys = append(m.DeploymentConfigKeys, key)
+		}
+		return nil
+	}
+
+	if err := m.completeClients(); err != nil {
+		return err
+	}
+	m.recorder = newEventRecorder(m.CoreClient)
+	if m.ValidateOnServer {
+		if err := m.checkServerDryRun(); err != nil {
+			return err
+		}
+	}
+
+	return m.completeDeploymentConfigKeys()
+}
+
+// completeClients loads the client configuration, resolves the namespace of the deployment configs
+// given without namespace and creates the clients.
+func (m *MigrateOptions) completeClients() error {
+	clientConfig := m.clientConfig()
+	config, err := clientConfig.ClientConfig()
 	if err != nil {
 		return err
 	}
+	config.QPS = m.QPS
+	config.Burst = m.Burst
+	if m.QPS > 0 {
+		// The clients of every migration share the rate limiter, so --qps limits all the migrations together
+		config.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(m.QPS, m.Burst)
+	}
+	if len(m.impersonateGroups) > 0 && len(m.impersonate) == 0 {
+		return fmt.Errorf("--as-group requires --as")
+	}
+	if len(m.impersonate) > 0 {
+		config.Impersonate.UserName = m.impersonate
+		config.Impersonate.Groups = m.impersonateGroups
+	}
+	if len(m.FieldManager) > 0 {
+		wrapTransport := config.WrapTransport
+		config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+			if wrapTransport != nil {
+				rt = wrapTransport(rt)
+			}
+			return &fieldManagerRoundTripper{fieldManager: m.FieldManager, delegate: rt}
+		}
+	}
+
+	if len(m.Namespace) == 0 {
+		// Namespace() falls back to the "default" namespace when the current context has none
+		m.Namespace, _, err = clientConfig.Namespace()
+		if err != nil {
+			return err
+		}
+	}
+	for i, key := range m.DeploymentConfigKeys {
+		if namespace, name := splitDeploymentConfigKey(key); len(namespace) == 0 {
+			m.DeploymentConfigKeys[i] = deploymentConfigKey(m.Namespace, name)
+		}
+	}
 
+	m.restConfig = config
+	return m.newClients(config)
+}
+
+// newClients creates the clients from the client configuration.
+func (m *MigrateOptions) newClients(config *rest.Config) error {
+	var err error
 	m.AppsClient, err = appsv1client.NewForConfig(config)
 	if err != nil {
 		return err
@@ -87,109 +493,1617 @@ func (m *MigrateOptions) Complete(c *cobra.Command) error {
 		return err
 	}
 
+	m.AutoscalingClient, err = autoscalingv1client.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	m.PolicyClient, err = policyv1beta1client.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	m.BatchClient, err = batchv1client.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	m.ImageClient, err = imagev1client.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	m.ConverterOptions.ImageResolver = converter.NewImageStreamTagResolver(m.ImageClient)
+
 	return nil
 }
 
-func (m *MigrateOptions) progress(message string) {
-	fmt.Fprintf(m.Output, color.Bold("-->").String()+" "+message+"\n")
+// withContext returns the copy of the options bound to the context of the migration. The API requests of the
+// copy are made with the context, as the typed clients do not accept contexts. Without the client
+// configuration, e.g. in the tests, the clients are kept.
+func (m *MigrateOptions) withContext(ctx context.Context) (*MigrateOptions, error) {
+	bound := *m
+	bound.ctx = ctx
+	if m.restConfig == nil {
+		return &bound, nil
+	}
+	config := rest.CopyConfig(m.restConfig)
+	wrapTransport := config.WrapTransport
+	config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		if wrapTransport != nil {
+			rt = wrapTransport(rt)
+		}
+		// The context is read on every request, so the rollback can replace the expired context
+		return &contextRoundTripper{context: func() context.Context { return bound.ctx }, delegate: rt}
+	}
+	if err := bound.newClients(config); err != nil {
+		return nil, err
+	}
+	return &bound, nil
 }
 
-func (m *MigrateOptions) Run() error {
-	for _, name := range m.DeploymentConfigNames {
-		m.progress(fmt.Sprintf("processing deployment config %q ...", color.Blue(m.Namespace+"/"+name)))
-		dc, err := m.OsAppsClient.DeploymentConfigs(m.Namespace).Get(name, metav1.GetOptions{})
+// completeDeploymentConfigKeys lists the deployment configs to migrate from the namespace when they are
+// not given by name, otherwise it checks that the named deployment configs exist.
+func (m *MigrateOptions) completeDeploymentConfigKeys() error {
+	if !m.listDeploymentConfigs() {
+		return m.checkDeploymentConfigsExist()
+	}
+	namespace := m.Namespace
+	if m.AllNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+	dcs, err := m.OsAppsClient.DeploymentConfigs(namespace).List(metav1.ListOptions{LabelSelector: m.Selector})
+	if err != nil {
+		return err
+	}
+	for _, dc := range dcs.Items {
+		m.DeploymentConfigKeys = append(m.DeploymentConfigKeys, deploymentConfigKey(dc.Namespace, dc.Name))
+	}
+	// The deployment configs are migrated grouped by namespace
+	sort.Strings(m.DeploymentConfigKeys)
+	return nil
+}
+
+// checkDeploymentConfigsExist returns an error listing the deployment configs given by name that do not exist,
+// so a typo in one of the names does not abort the migration after the other deployment configs were paused.
+func (m *MigrateOptions) checkDeploymentConfigsExist() error {
+	missing := []string{}
+	for _, key := range m.DeploymentConfigKeys {
+		_, err := m.getDeploymentConfig(key)
+		if errors.IsNotFound(err) {
+			missing = append(missing, key)
+			continue
+		}
 		if err != nil {
 			return err
 		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("deployment configs not found: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
 
-		m.progress(fmt.Sprintf("pausing deployment config %q ...", color.Blue(dc.Namespace+"/"+dc.Name)))
-		dc.Spec.Paused = true
-		_, err = m.OsAppsClient.DeploymentConfigs(m.Namespace).Update(dc)
-		if err != nil {
-			return err
+// offline returns true when the deployment configs are read from files and no cluster is used.
+func (m *MigrateOptions) offline() bool {
+	return len(m.FromFiles) > 0
+}
+
+// getDeploymentConfig returns the deployment config from the cluster or from the files.
+func (m *MigrateOptions) getDeploymentConfig(key string) (*osappsv1.DeploymentConfig, error) {
+	if m.offline() {
+		dc, ok := m.fileDeploymentConfigs[key]
+		if !ok {
+			return nil, fmt.Errorf("deployment config %q not found in %s", key, strings.Join(m.FromFiles, ", "))
 		}
+		return dc, nil
+	}
+	namespace, name := splitDeploymentConfigKey(key)
+	return m.OsAppsClient.DeploymentConfigs(namespace).Get(name, metav1.GetOptions{})
+}
+
+// optionalInt32 returns the pointer to the value, or nil for zero, so the Kubernetes default applies.
+func optionalInt32(value int32) *int32 {
+	if value == 0 {
+		return nil
+	}
+	return &value
+}
 
-		var deployment *appsv1.Deployment
+// parseIntOrPercent parses the non-negative number or percentage, like "1" or "25%".
+func parseIntOrPercent(value string) (intstr.IntOrString, error) {
+	number := strings.TrimSuffix(value, "%")
+	i, err := strconv.Atoi(number)
+	if err != nil || i < 0 {
+		return intstr.IntOrString{}, fmt.Errorf("%q must be a non-negative number or percentage", value)
+	}
+	if number != value {
+		return intstr.FromString(value), nil
+	}
+	return intstr.FromInt(i), nil
+}
+
+// isZeroIntOrPercent returns true for the zero number or percentage parsed by parseIntOrPercent.
+func isZeroIntOrPercent(value intstr.IntOrString) bool {
+	i, _ := strconv.Atoi(strings.TrimSuffix(value.String(), "%"))
+	return i == 0
+}
 
-		m.progress(fmt.Sprintf("converting deployment config %q to kubernetes deployment...", color.Blue(dc.Namespace+"/"+dc.Name)))
-		err = m.convert(dc, deployment)
+// parseDeploymentConfigArgs adds the deployment configs given as arguments to the deployment configs to migrate.
+// The deployment configs without namespace get the default namespace in completeClients().
+func (m *MigrateOptions) parseDeploymentConfigArgs(args []string) error {
+	for _, arg := range args {
+		namespace, name, err := parseDeploymentConfigArg(arg)
 		if err != nil {
 			return err
 		}
+		m.DeploymentConfigKeys = append(m.DeploymentConfigKeys, deploymentConfigKey(namespace, name))
+	}
+	return nil
+}
 
-		// Pause deployment so we can finish transition
-		deployment.Spec.Paused = true
+// completeDeploymentConfigArgs parses the deployment config arguments of the verify and undo commands and
+// creates the clients. The argument errors are validation errors.
+func (m *MigrateOptions) completeDeploymentConfigArgs(args []string) error {
+	if len(args) == 0 {
+		return &validationError{fmt.Errorf("deployment config name(s) must be specified")}
+	}
+	if err := m.parseDeploymentConfigArgs(args); err != nil {
+		return &validationError{err}
+	}
+	return m.loadClients()
+}
 
-		m.progress(fmt.Sprintf("creating paused deployment %q ...", color.Blue(deployment.Namespace+"/"+deployment.Name)))
-		newDeployment, err := m.AppsClient.Deployments(m.Namespace).Create(deployment)
-		if err != nil {
+// parseDeploymentConfigArg parses the deployment config argument in the [namespace/][dc/]name form.
+func parseDeploymentConfigArg(arg string) (namespace, name string, err error) {
+	parts := strings.Split(arg, "/")
+	// The resource type prefix is optional
+	if len(parts) > 1 && parts[len(parts)-2] == "dc" {
+		parts = append(parts[:len(parts)-2], parts[len(parts)-1])
+	}
+	switch len(parts) {
+	case 1:
+		name = parts[0]
+	case 2:
+		namespace, name = parts[0], parts[1]
+	}
+	if len(parts) > 2 || len(name) == 0 || (len(parts) == 2 && len(namespace) == 0) {
+		return "", "", fmt.Errorf("invalid deployment config name %q", arg)
+	}
+	return namespace, name, nil
+}
+
+// deploymentConfigKey returns the namespace/name key of the deployment config, the key of deployment
+// config without namespace is the name.
+func deploymentConfigKey(namespace, name string) string {
+	if len(namespace) == 0 {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+// splitDeploymentConfigKey returns the namespace and name of the deployment config key.
+func splitDeploymentConfigKey(key string) (namespace, name string) {
+	if i := strings.Index(key, "/"); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return "", key
+}
+
+// listDeploymentConfigs returns true when the deployment configs to migrate are listed from
+// the namespace instead of given by name.
+func (m *MigrateOptions) listDeploymentConfigs() bool {
+	return m.All || m.AllNamespaces || len(m.Selector) > 0
+}
+
+func (m *MigrateOptions) Run() error {
+	// When migrating multiple deployment configs, a single failure does not abort the whole batch
+	continueOnError := len(m.DeploymentConfigKeys) > 1 && !m.FailFast
+
+	if err := m.confirm(); err != nil {
+		return err
+	}
+
+	var (
+		lock    sync.Mutex
+		aborted bool
+		wg      sync.WaitGroup
+	)
+	errs := make([]error, len(m.DeploymentConfigKeys))
+	m.results = make([]migrationResult, len(m.DeploymentConfigKeys))
+	queue := make(chan int)
+	for i := 0; i < m.Parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range queue {
+				lock.Lock()
+				skip := aborted
+				lock.Unlock()
+				if skip {
+					continue
+				}
+				err := m.migrateWithOutput(index, continueOnError, &lock)
+				lock.Lock()
+				errs[index] = err
+				aborted = aborted || (err != nil && !continueOnError)
+				lock.Unlock()
+			}
+		}()
+	}
+	for index := range m.DeploymentConfigKeys {
+		queue <- index
+	}
+	close(queue)
+	wg.Wait()
+
+	if m.ResultFormat == "json" {
+		if err := printResults(m.Output, m.results); err != nil {
 			return err
 		}
+	}
 
-		// TODO: Move this to openshift/api
-		selector := labels.SelectorFromValidatedSet(labels.Set{"openshift.io/deployment-config.name": dc.Name})
-		rcs, err := m.CoreClient.ReplicationControllers(m.Namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+	if m.OutputFormat == "kustomize" && len(m.written.paths) > 0 {
+		path, err := writeKustomization(m.OutputDir, m.Overwrite, m.written.paths)
 		if err != nil {
 			return err
 		}
+		m.progress("written", "", fmt.Sprintf("wrote %q", path))
+	}
 
-		if len(rcs.Items) > 0 {
-			m.progress(fmt.Sprintf("found %d replication controllers managed by %q:", len(rcs.Items),
-				color.Blue(deployment.Namespace+"/"+deployment.Name)))
-			for _, rc := range rcs.Items {
-				m.progress(fmt.Sprintf("  --> %s", color.Gray(rc.Name)))
-			}
+	failed := []error{}
+	for index, err := range errs {
+		if err == nil {
+			continue
 		}
-
-		err = m.migrateHistory(newDeployment, rcs.Items)
-		if err != nil {
+		if !continueOnError {
 			return err
 		}
+		failed = append(failed, fmt.Errorf("deployment config %q: %v", m.DeploymentConfigKeys[index], err))
+	}
+
+	if m.AllNamespaces {
+		m.namespaceSummary(errs)
+	}
+	if len(m.DeploymentConfigKeys) > 1 {
+		m.progress("summary", "", fmt.Sprintf("migrated %d of %d deployment configs", len(m.DeploymentConfigKeys)-len(failed),
+			len(m.DeploymentConfigKeys)))
+	}
+	if len(failed) > 0 {
+		return &partialFailureError{utilerrors.NewAggregate(failed)}
 	}
 	return nil
 }
 
-func NewMigrateCommand(out io.Writer) *cobra.Command {
-	options := &MigrateOptions{Output: out}
-
-	cmd := &cobra.Command{
-		Use:   "migrate-to-deployment",
-		Short: "This command migrate your deployment config to kubernetes deployment",
-		Run: func(cmd *cobra.Command, args []string) {
-			if err := options.Validate(cmd); err != nil {
-				fmt.Fprintf(os.Stderr, color.Red("ERROR:").String()+" %v\n", err)
-				os.Exit(1)
+// namespaceSummary prints the number of migrated deployment configs in every namespace.
+// The errors are indexed the same way as the deployment config keys, which are sorted by namespace.
+func (m *MigrateOptions) namespaceSummary(errs []error) {
+	for start := 0; start < len(m.DeploymentConfigKeys); {
+		namespace, _ := splitDeploymentConfigKey(m.DeploymentConfigKeys[start])
+		end, migrated := start, 0
+		for ; end < len(m.DeploymentConfigKeys); end++ {
+			if ns, _ := splitDeploymentConfigKey(m.DeploymentConfigKeys[end]); ns != namespace {
+				break
 			}
-			if err := options.Complete(cmd); err != nil {
-				fmt.Fprintf(os.Stderr, color.Red("ERROR:").String()+" %v\n", err)
-				os.Exit(1)
-			}
-			if err := options.Run(); err != nil {
-				fmt.Fprintf(os.Stderr, color.Red("ERROR:").String()+" %v\n", err)
-				os.Exit(1)
+			if errs[end] == nil {
+				migrated++
 			}
-		},
+		}
+		m.progress("summary", "", fmt.Sprintf("namespace %q: migrated %d of %d deployment configs", m.color.Blue(namespace),
+			migrated, end-start))
+		start = end
 	}
+}
 
-	if home := homeDir(); len(home) > 0 {
-		cmd.Flags().StringVar(&options.kubeconfig, "kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
-	} else {
-		cmd.Flags().StringVar(&options.kubeconfig, "kubeconfig", "", "absolute path to the kubeconfig file")
+// confirm asks the user to confirm the migration of the deployment configs before any cluster
+// object is mutated. When the input is not a terminal, the confirmation must be given by --yes.
+func (m *MigrateOptions) confirm() error {
+	if m.Yes || m.DryRun || len(m.DeploymentConfigKeys) == 0 {
+		return nil
+	}
+	if !m.interactive {
+		return fmt.Errorf("the input is not a terminal, use --yes to confirm the migration")
+	}
+	fmt.Fprintln(m.logOutput(), "The following deployment configs will be migrated to deployments:")
+	for _, key := range m.DeploymentConfigKeys {
+		fmt.Fprintf(m.logOutput(), "  %s\n", m.color.Blue(key))
+	}
+	fmt.Fprint(m.logOutput(), "Type 'yes' to continue: ")
+	answer, err := bufio.NewReader(m.Input).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if strings.TrimSpace(answer) != "yes" {
+		return fmt.Errorf("migration aborted")
+	}
+	return nil
+}
+
+// migrateWithOutput migrates the deployment config and prints the error when the batch continues on errors.
+// When migrating in parallel, the output of every deployment config is buffered and flushed at once
+// under the lock, so the output of concurrent migrations is not interleaved.
+func (m *MigrateOptions) migrateWithOutput(index int, continueOnError bool, lock *sync.Mutex) error {
+	key := m.DeploymentConfigKeys[index]
+	migrator := m
+	var out, errOut *bytes.Buffer
+	if m.Parallelism > 1 {
+		out, errOut = &bytes.Buffer{}, &bytes.Buffer{}
+		buffered := *m
+		buffered.Output, buffered.ErrOut = out, errOut
+		migrator = &buffered
 	}
 
-	cmd.Flags().StringVarP(&options.Namespace, "namespace", "n", "", "namespace to use (default: current namespace)")
+	migrator.steps = &stepCounter{index: index + 1, total: len(m.DeploymentConfigKeys), started: time.Now()}
+	migrator.result = &m.results[index]
+	migrator.result.DeploymentConfig = key
+	err := migrator.migrate(key)
+	migrator.result.setStatus(err, m.DryRun)
+	if err != nil && continueOnError {
+		migrator.error("failed", key, err)
+	}
+	migrator.progress("finished", key, fmt.Sprintf("finished deployment config %q in %s", migrator.color.Blue(key),
+		time.Since(migrator.steps.started).Round(time.Millisecond)))
+	migrator.steps = nil
 
-	cmd.SetUsageFunc(func(c *cobra.Command) error {
-		fmt.Fprintf(os.Stderr, "Usage: %s dc/foo dc/ba\nr", c.Name())
-		return nil
-	})
+	if out != nil {
+		lock.Lock()
+		defer lock.Unlock()
+		m.Output.Write(out.Bytes())
+		m.ErrOut.Write(errOut.Bytes())
+	}
+	return err
+}
 
-	return cmd
+// migrate migrates a single deployment config to deployment within the --timeout deadline. The API requests
+// and the waits are canceled when the deadline expires and the migration is rolled back.
+func (m *MigrateOptions) migrate(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.Timeout)
+	defer cancel()
+	migrator, err := m.withContext(ctx)
+	if err != nil {
+		return err
+	}
+	return migrator.migrateDeploymentConfig(key)
 }
 
-func homeDir() string {
-	if h := os.Getenv("HOME"); h != "" {
-		return h
+// migrateDeploymentConfig migrates a single deployment config to deployment.
+// When the migration fails after the deployment config was paused, the objects created or changed by the
+// migration are reverted and the deployment config pause is reverted.
+func (m *MigrateOptions) migrateDeploymentConfig(key string) (err error) {
+	m.progress("processing", key, fmt.Sprintf("processing deployment config %q ...", m.color.Blue(key)))
+	dc, err := m.getDeploymentConfig(key)
+	if err != nil {
+		return err
+	}
+
+	// Deployment configs migrated by a previous run are skipped, so the migration can be re-run
+	if !m.DryRun && !m.ReMigrate {
+		existing, err := m.AppsClient.Deployments(dc.Namespace).Get(dc.Name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if err == nil && existing.Annotations[converter.SourceDeploymentConfigAnnotation] == dc.Name {
+			m.warning("already-migrated", key, fmt.Sprintf("deployment config %q was already migrated to deployment %q, skipping (use --re-migrate to migrate it again)",
+				m.color.Blue(key), m.color.Blue(existing.Namespace+"/"+existing.Name)))
+			if m.result != nil {
+				m.result.Status = resultSkipped
+			}
+			return nil
+		}
+	}
+
+	hpas, err := m.findHorizontalPodAutoscalers(dc)
+	if err != nil {
+		return err
+	}
+	report := converter.NewReport(dc.Namespace, dc.Name)
+	defer func() {
+		if err == nil {
+			m.printReport(key, report)
+		}
+	}()
+
+	convertOptions := m.ConverterOptions
+	convertOptions.Report = report
+	convertOptions.MigratedAt = time.Now()
+	if len(hpas) > 0 {
+		// The deployment replicas are only left to the horizontal pod autoscaler when it is repointed,
+		// otherwise nothing would scale the deployment
+		convertOptions.Autoscaled = m.MigrateHPA
+		for _, hpa := range hpas {
+			if m.MigrateHPA {
+				m.progress("autoscaled", dc.Namespace+"/"+dc.Name, fmt.Sprintf("deployment config %q is scaled by horizontal pod autoscaler %q, it will be repointed to the deployment",
+					m.color.Blue(dc.Namespace+"/"+dc.Name), m.color.Gray(hpa.Name)))
+				continue
+			}
+			m.warning("autoscaled", dc.Namespace+"/"+dc.Name, fmt.Sprintf("deployment config %q is scaled by horizontal pod autoscaler %q, repoint it to the deployment after the migration",
+				m.color.Blue(dc.Namespace+"/"+dc.Name), m.color.Gray(hpa.Name)))
+		}
+	}
+
+	// The replicas of the running replication controller may differ from the deployment config replicas, for
+	// example after it was scaled directly. Autoscaled deployments keep their replicas unset.
+	if m.PreserveReplicasFromRC && !convertOptions.Autoscaled {
+		rcs, err := m.findReplicationControllers(dc)
+		if err != nil {
+			return err
+		}
+		if rc := converter.LatestCompleteReplicationController(rcs); rc != nil && rc.Spec.Replicas != nil {
+			replicas := *rc.Spec.Replicas
+			convertOptions.Replicas = &replicas
+			m.progress("replicas", dc.Namespace+"/"+dc.Name, fmt.Sprintf("using %d replicas of the active replication controller %q", replicas, m.color.Gray(rc.Name)))
+		} else {
+			m.warning("replicas", dc.Namespace+"/"+dc.Name, fmt.Sprintf("deployment config %q has no complete rollout, using the deployment config replicas",
+				m.color.Blue(dc.Namespace+"/"+dc.Name)))
+		}
+	}
+
+	m.progress("converting", dc.Namespace+"/"+dc.Name, fmt.Sprintf("converting deployment config %q to kubernetes deployment...", m.color.Blue(dc.Namespace+"/"+dc.Name)))
+	deployment, err := m.convert(dc, convertOptions)
+	if err != nil {
+		return err
+	}
+	if dc.Spec.Strategy.Type == osappsv1.DeploymentStrategyTypeCustom {
+		m.warning("strategy-forced", dc.Namespace+"/"+dc.Name, fmt.Sprintf("deployment config %q custom deployment strategy was FORCED to rolling update, the custom deployment logic is lost",
+			m.color.Blue(dc.Namespace+"/"+dc.Name)))
+	}
+	if m.VerifyImageExists {
+		warnings, err := converter.VerifyImages(deployment)
+		if err != nil {
+			return err
+		}
+		for _, warning := range warnings {
+			m.warning("floating-tag", dc.Namespace+"/"+dc.Name, fmt.Sprintf("deployment %q %s, pin the tag or digest to avoid rolling out a different image",
+				m.color.Blue(deployment.Namespace+"/"+deployment.Name), warning))
+		}
+	}
+	for _, from := range converter.CrossNamespaceImageTriggers(dc) {
+		m.warning("cross-namespace-trigger", dc.Namespace+"/"+dc.Name, fmt.Sprintf("deployment config %q image change trigger references %s %q in namespace %q, "+
+			"the deployment has the image hard-coded and does not roll out when it changes", m.color.Blue(dc.Namespace+"/"+dc.Name), from.Kind, from.Name, from.Namespace))
+	}
+	if converter.HasDeprecatedAffinity(dc) {
+		m.warning("deprecated-affinity", dc.Namespace+"/"+dc.Name, fmt.Sprintf("deployment config %q pod template uses the deprecated %q annotation which is ignored by the scheduler, "+
+			"move the affinity into the pod template affinity field", m.color.Blue(dc.Namespace+"/"+dc.Name), converter.DeprecatedAffinityAnnotation))
+	}
+
+	var hpa *autoscalingv1.HorizontalPodAutoscaler
+	switch {
+	case m.MaxReplicas > 0 && len(hpas) > 0:
+		m.warning("autoscaled", dc.Namespace+"/"+dc.Name, fmt.Sprintf("deployment config %q is already autoscaled, no horizontal pod autoscaler is generated",
+			m.color.Blue(dc.Namespace+"/"+dc.Name)))
+	case m.MaxReplicas > 0:
+		hpa = converter.HorizontalPodAutoscaler(deployment, optionalInt32(m.MinReplicas), m.MaxReplicas, optionalInt32(m.CPUTarget))
 	}
-	return os.Getenv("USERPROFILE") // windows
+
+	var preHookJobs, postHookJobs []*batchv1.Job
+	switch {
+	case converter.HasLifecycleHooks(dc) && m.Hooks == "jobs":
+		preHookJobs, postHookJobs, err = converter.LifecycleHookJobs(dc, deployment)
+		if err != nil {
+			return err
+		}
+		report.Approximatedf("%s strategy pre/post lifecycle hooks converted to jobs", dc.Spec.Strategy.Type)
+		if converter.HasMidLifecycleHook(dc) {
+			report.Droppedf("Recreate strategy mid lifecycle hook")
+			m.warning("hook-dropped", dc.Namespace+"/"+dc.Name, fmt.Sprintf("deployment config %q mid lifecycle hook cannot be converted to job and will be dropped",
+				m.color.Blue(dc.Namespace+"/"+dc.Name)))
+		}
+	case converter.HasLifecycleHooks(dc) && m.Hooks == "init":
+		converted, err := converter.LifecycleHookInitContainer(dc, deployment)
+		if err != nil {
+			return err
+		}
+		if converted {
+			report.Approximatedf("%s strategy pre lifecycle hook converted to init container (runs before every pod start)", dc.Spec.Strategy.Type)
+		}
+		if converter.HasMidLifecycleHook(dc) || converter.HasPostLifecycleHook(dc) {
+			report.Droppedf("%s strategy mid and post lifecycle hooks", dc.Spec.Strategy.Type)
+			m.warning("hook-dropped", dc.Namespace+"/"+dc.Name, fmt.Sprintf("deployment config %q mid and post lifecycle hooks cannot be converted to init containers and will be dropped",
+				m.color.Blue(dc.Namespace+"/"+dc.Name)))
+		}
+	case converter.HasLifecycleHooks(dc):
+		report.Droppedf("%s strategy lifecycle hooks", dc.Spec.Strategy.Type)
+		m.warning("hook-dropped", dc.Namespace+"/"+dc.Name, fmt.Sprintf("deployment config %q %s strategy lifecycle hooks cannot be represented in deployment and will be dropped (see --hooks)",
+			m.color.Blue(dc.Namespace+"/"+dc.Name), dc.Spec.Strategy.Type))
+	}
+
+	services, err := m.findServices(dc)
+	if err != nil {
+		return err
+	}
+	if len(services) > 0 && m.ServiceFixup == "label" {
+		// Keep the label the services select on in the deployment pods
+		if deployment.Spec.Template.Labels == nil {
+			deployment.Spec.Template.Labels = map[string]string{}
+		}
+		deployment.Spec.Template.Labels[converter.DeploymentConfigLabel] = dc.Name
+	}
+
+	pdbs, err := m.findPodDisruptionBudgets(dc, deployment)
+	if err != nil {
+		return err
+	}
+	for _, pdb := range pdbs {
+		if m.MigratePDB {
+			continue
+		}
+		m.warning("pdb", dc.Namespace+"/"+dc.Name, fmt.Sprintf("pod disruption budget %q selects deployment config %q pods but not the deployment pods, "+
+			"update its selector (see --migrate-pdb)", m.color.Blue(pdb.Namespace+"/"+pdb.Name), m.color.Blue(dc.Namespace+"/"+dc.Name)))
+	}
+
+	// Pause deployment so we can finish transition
+	deployment.Spec.Paused = true
+
+	if m.DryRun {
+		if m.Diff {
+			m.printDiff(m.Output, dc, deployment)
+		}
+		rcs, err := m.listReplicationControllers(dc)
+		if err != nil {
+			return err
+		}
+		objects := []kruntime.Object{deployment}
+		if hpa != nil {
+			objects = append(objects, hpa)
+		}
+		for _, job := range append(preHookJobs, postHookJobs...) {
+			objects = append(objects, job)
+		}
+		replicaSets, err := converter.MigrateHistory(deployment, rcs)
+		if err != nil {
+			return err
+		}
+		for _, rs := range replicaSets {
+			objects = append(objects, rs)
+		}
+		return m.outputObjects(dc.Namespace+"/"+dc.Name, objects...)
+	}
+
+	replace := false
+	existing, err := m.AppsClient.Deployments(deployment.Namespace).Get(deployment.Name, metav1.GetOptions{})
+	switch {
+	// Deployments of re-migrated deployment configs are replaced, other deployments only with --overwrite
+	case err == nil && !m.Overwrite && existing.Annotations[converter.SourceDeploymentConfigAnnotation] != dc.Name:
+		return fmt.Errorf("deployment %q already exists, use --overwrite to replace it", deployment.Namespace+"/"+deployment.Name)
+	case err == nil:
+		replace = true
+		deployment.ResourceVersion = existing.ResourceVersion
+	case !errors.IsNotFound(err):
+		return err
+	}
+
+	if m.ValidateOnServer {
+		if err := m.validateDeployment(deployment, replace); err != nil {
+			return err
+		}
+	}
+
+	m.event(key, dc, "Converted", fmt.Sprintf("Converted to deployment %s", deployment.Name))
+
+	// A deployment config paused by its owner stays paused and so does the deployment. The original paused
+	// state is recorded, so a re-run or undo of the migration can tell it from the migration pause.
+	original := dc
+	wasPaused := dc.Spec.Paused
+	if value, ok := dc.Annotations[converter.OriginalPausedAnnotation]; ok {
+		wasPaused = value == "true"
+	}
+	m.progress("pausing", dc.Namespace+"/"+dc.Name, fmt.Sprintf("pausing deployment config %q ...", m.color.Blue(dc.Namespace+"/"+dc.Name)))
+	dc, err = m.updateDeploymentConfig(dc, func(dc *osappsv1.DeploymentConfig) {
+		dc.Spec.Paused = true
+		if dc.Annotations == nil {
+			dc.Annotations = map[string]string{}
+		}
+		dc.Annotations[converter.OriginalPausedAnnotation] = strconv.FormatBool(wasPaused)
+	})
+	if err != nil {
+		return err
+	}
+	m.event(key, dc, "Paused", "Paused for the migration to deployment")
+
+	// Every change made from now on is reverted when the migration fails, so the deployment config keeps
+	// running the application and the migration can be re-run
+	var rollback []rollbackAction
+	defer func() {
+		if err == nil || len(rollback) == 0 {
+			return
+		}
+		// The rollback must not be canceled by the expired deadline of the migration
+		ctx, cancel := context.WithTimeout(context.Background(), m.Timeout)
+		defer cancel()
+		m.ctx = ctx
+		if m.rollback(key, rollback) {
+			err = &rolledBackError{err}
+		}
+	}()
+	pausedDC := dc
+	rollback = append(rollback, rollbackAction{
+		description: fmt.Sprintf("restoring deployment config %q pause", dc.Namespace+"/"+dc.Name),
+		revert:      func() error { return m.restorePause(pausedDC, original) },
+	})
+
+	createdJobs, err := m.createHookJobs(preHookJobs)
+	rollback = append(rollback, m.deleteJobActions(createdJobs)...)
+	if err != nil {
+		return err
+	}
+	// The pre hook must complete before the deployment is created, the same way the deployment config
+	// controller runs it before the rollout
+	for _, job := range createdJobs {
+		if err := m.waitForHookJob(job); err != nil {
+			return err
+		}
+	}
+
+	var newDeployment *appsv1.Deployment
+	if m.Apply == "ssa" {
+		m.progress("applying", dc.Namespace+"/"+dc.Name, fmt.Sprintf("applying paused deployment %q ...", m.color.Blue(deployment.Namespace+"/"+deployment.Name)))
+		newDeployment, err = m.applyDeployment(deployment)
+	} else if replace {
+		m.progress("replacing", dc.Namespace+"/"+dc.Name, fmt.Sprintf("replacing existing deployment %q with paused deployment ...", m.color.Blue(deployment.Namespace+"/"+deployment.Name)))
+		newDeployment, err = m.AppsClient.Deployments(deployment.Namespace).Update(deployment)
+	} else {
+		m.progress("creating", dc.Namespace+"/"+dc.Name, fmt.Sprintf("creating paused deployment %q ...", m.color.Blue(deployment.Namespace+"/"+deployment.Name)))
+		newDeployment, err = m.AppsClient.Deployments(deployment.Namespace).Create(deployment)
+	}
+	if err != nil {
+		return err
+	}
+	if replace {
+		previous := existing
+		rollback = append(rollback, rollbackAction{
+			description: fmt.Sprintf("restoring deployment %q", previous.Namespace+"/"+previous.Name),
+			revert:      func() error { return m.restoreDeployment(previous) },
+		})
+	} else {
+		rollback = append(rollback, rollbackAction{
+			description: fmt.Sprintf("deleting deployment %q", newDeployment.Namespace+"/"+newDeployment.Name),
+			revert: func() error {
+				return ignoreNotFound(m.AppsClient.Deployments(newDeployment.Namespace).Delete(newDeployment.Name, deleteOptions()))
+			},
+		})
+	}
+	m.event(key, dc, "DeploymentCreated", fmt.Sprintf("Migrated to deployment %s", newDeployment.Name))
+	m.event(key, newDeployment, "Migrated", fmt.Sprintf("Migrated from deployment config %s", dc.Name))
+
+	rcs, err := m.listReplicationControllers(dc)
+	if err != nil {
+		return err
+	}
+
+	replicaSets, err := m.migrateHistory(newDeployment, rcs)
+	for _, rs := range replicaSets {
+		createdRS := rs
+		rollback = append(rollback, rollbackAction{
+			description: fmt.Sprintf("deleting replica set %q", createdRS.Namespace+"/"+createdRS.Name),
+			revert: func() error {
+				return ignoreNotFound(m.AppsClient.ReplicaSets(createdRS.Namespace).Delete(createdRS.Name, &metav1.DeleteOptions{}))
+			},
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if len(replicaSets) > 0 {
+		m.event(key, newDeployment, "HistoryMigrated", fmt.Sprintf("Migrated %d replication controllers of deployment config %s to replica sets",
+			len(replicaSets), dc.Name))
+	}
+
+	if m.ServiceFixup == "selector" {
+		for i := range services {
+			service, selector := services[i].Name, services[i].DeepCopy().Spec.Selector
+			rollback = append(rollback, rollbackAction{
+				description: fmt.Sprintf("restoring service %q selector", dc.Namespace+"/"+service),
+				revert:      func() error { return m.restoreServiceSelector(dc.Namespace, service, selector) },
+			})
+			if err := m.updateServiceSelector(&services[i], dc, newDeployment); err != nil {
+				return err
+			}
+		}
+	}
+
+	if m.MigratePDB {
+		for i := range pdbs {
+			pdb := pdbs[i].DeepCopy()
+			rollback = append(rollback, rollbackAction{
+				description: fmt.Sprintf("restoring pod disruption budget %q", pdb.Namespace+"/"+pdb.Name),
+				revert:      func() error { return m.restorePodDisruptionBudget(pdb, pdb.Spec.Selector) },
+			})
+			if err := m.retargetPodDisruptionBudget(&pdbs[i], newDeployment); err != nil {
+				return err
+			}
+		}
+	}
+
+	if m.MigrateHPA {
+		for i := range hpas {
+			original := hpas[i].DeepCopy()
+			rollback = append(rollback, rollbackAction{
+				description: fmt.Sprintf("repointing horizontal pod autoscaler %q to deployment config %q", original.Namespace+"/"+original.Name, dc.Namespace+"/"+dc.Name),
+				revert: func() error {
+					return m.restoreScaleTargetRef(original.Namespace, original.Name, original.Spec.ScaleTargetRef)
+				},
+			})
+			if err := m.retargetHorizontalPodAutoscaler(&hpas[i], newDeployment); err != nil {
+				return err
+			}
+		}
+	}
+
+	if hpa != nil {
+		m.progress("creating-hpa", dc.Namespace+"/"+dc.Name, fmt.Sprintf("creating horizontal pod autoscaler %q ...", m.color.Blue(hpa.Namespace+"/"+hpa.Name)))
+		newHPA, err := m.AutoscalingClient.HorizontalPodAutoscalers(hpa.Namespace).Create(hpa)
+		switch {
+		case errors.IsAlreadyExists(err):
+			var previous *autoscalingv1.HorizontalPodAutoscaler
+			if previous, newHPA, err = m.updateGeneratedHorizontalPodAutoscaler(dc, hpa); err == nil {
+				rollback = append(rollback, rollbackAction{
+					description: fmt.Sprintf("restoring horizontal pod autoscaler %q", previous.Namespace+"/"+previous.Name),
+					revert: func() error {
+						return m.updateHorizontalPodAutoscaler(previous, func(hpa *autoscalingv1.HorizontalPodAutoscaler) {
+							hpa.Annotations = previous.Annotations
+							hpa.Spec = previous.Spec
+						})
+					},
+				})
+			}
+		case err == nil:
+			createdHPA := newHPA
+			rollback = append(rollback, rollbackAction{
+				description: fmt.Sprintf("deleting horizontal pod autoscaler %q", createdHPA.Namespace+"/"+createdHPA.Name),
+				revert: func() error {
+					return ignoreNotFound(m.AutoscalingClient.HorizontalPodAutoscalers(createdHPA.Namespace).Delete(createdHPA.Name, &metav1.DeleteOptions{}))
+				},
+			})
+		}
+		if err != nil {
+			return err
+		}
+		hpa = newHPA
+	}
+
+	keepPaused := m.KeepPaused
+	if converter.IsManualRollout(newDeployment) && m.PauseManualRollouts {
+		m.progress("manual-rollout", dc.Namespace+"/"+dc.Name, fmt.Sprintf("deployment config %q was rolled out manually, leaving deployment %q paused (resume it to roll out)",
+			m.color.Blue(dc.Namespace+"/"+dc.Name), m.color.Blue(newDeployment.Namespace+"/"+newDeployment.Name)))
+		keepPaused = true
+	}
+	if wasPaused {
+		m.progress("paused", dc.Namespace+"/"+dc.Name, fmt.Sprintf("deployment config %q is paused, leaving deployment %q paused (resume it to roll out)",
+			m.color.Blue(dc.Namespace+"/"+dc.Name), m.color.Blue(newDeployment.Namespace+"/"+newDeployment.Name)))
+		keepPaused = true
+	}
+
+	if !keepPaused {
+		m.progress("resuming", dc.Namespace+"/"+dc.Name, fmt.Sprintf("resuming deployment %q ...", m.color.Blue(newDeployment.Namespace+"/"+newDeployment.Name)))
+		newDeployment, err = m.AppsClient.Deployments(newDeployment.Namespace).Patch(newDeployment.Name, types.StrategicMergePatchType,
+			[]byte(`{"spec":{"paused":false}}`))
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.Wait && !keepPaused {
+		if err := m.waitForDeployment(newDeployment); err != nil {
+			return err
+		}
+		// The deployment config replication controllers are scaled down only once the deployment pods
+		// took over, the paused deployment config does not scale them back up
+		scaled, err := m.scaleDownReplicationControllers(dc)
+		for _, rc := range scaled {
+			scaledRC := rc
+			rollback = append(rollback, rollbackAction{
+				description: fmt.Sprintf("scaling up replication controller %q", scaledRC.Namespace+"/"+scaledRC.Name),
+				revert: func() error {
+					return m.restoreReplicationControllerReplicas(scaledRC.Namespace, scaledRC.Name, *scaledRC.Spec.Replicas)
+				},
+			})
+		}
+		if err != nil {
+			return err
+		}
+		// The post hook runs once the rollout completes
+		jobs, err := m.createHookJobs(postHookJobs)
+		rollback = append(rollback, m.deleteJobActions(jobs)...)
+		if err != nil {
+			return err
+		}
+		createdJobs = append(createdJobs, jobs...)
+		// The deployment config is only pruned once the deployment is available
+		if m.Prune {
+			m.progress("pruning", dc.Namespace+"/"+dc.Name, fmt.Sprintf("deleting deployment config %q ...", m.color.Blue(dc.Namespace+"/"+dc.Name)))
+			if err := m.OsAppsClient.DeploymentConfigs(dc.Namespace).Delete(dc.Name, deleteOptions()); err != nil {
+				return err
+			}
+			// The deployment is the only one running the application now, it must not be rolled back
+			rollback = nil
+		}
+	} else {
+		// The paused deployment does not roll out, so there is nothing to wait for and the deployment config
+		// still runs the application
+		if m.Wait && keepPaused {
+			skipped := "--wait"
+			if m.Prune {
+				skipped = "--wait and --prune"
+			}
+			m.warning("kept-paused", dc.Namespace+"/"+dc.Name, fmt.Sprintf("deployment %q is left paused, %s skipped, resume the deployment and delete deployment config %q once it is available",
+				m.color.Blue(newDeployment.Namespace+"/"+newDeployment.Name), skipped, m.color.Blue(dc.Namespace+"/"+dc.Name)))
+		}
+		if !keepPaused && !m.offline() {
+			m.warning("not-scaled-down", dc.Namespace+"/"+dc.Name, fmt.Sprintf("deployment config %q replication controllers keep running next to deployment %q pods, "+
+				"scale them down once the deployment is available (use --wait to scale them down)", m.color.Blue(dc.Namespace+"/"+dc.Name),
+				m.color.Blue(newDeployment.Namespace+"/"+newDeployment.Name)))
+		}
+		for _, job := range postHookJobs {
+			m.warning("hook-skipped", dc.Namespace+"/"+dc.Name, fmt.Sprintf("post lifecycle hook job %q is not created, the deployment %q rollout was not waited for "+
+				"(use --wait and resume the deployment to run it)", m.color.Blue(job.Namespace+"/"+job.Name), m.color.Blue(newDeployment.Namespace+"/"+newDeployment.Name)))
+		}
+	}
+
+	objects := []kruntime.Object{newDeployment}
+	if hpa != nil {
+		objects = append(objects, hpa)
+	}
+	for _, rs := range replicaSets {
+		objects = append(objects, rs)
+	}
+	for _, job := range createdJobs {
+		objects = append(objects, job)
+	}
+	return m.outputObjects(dc.Namespace+"/"+dc.Name, objects...)
+}
+
+// applyDeployment creates or updates the deployment using server-side apply with the field manager. The
+// conflicts with the fields owned by other managers are only forced with --overwrite.
+func (m *MigrateOptions) applyDeployment(deployment *appsv1.Deployment) (*appsv1.Deployment, error) {
+	applied := deployment.DeepCopy()
+	applied.ResourceVersion = ""
+	// The applied configuration must have the apiVersion and kind set
+	data, err := kruntime.Encode(scheme.Codecs.LegacyCodec(appsv1.SchemeGroupVersion), applied)
+	if err != nil {
+		return nil, err
+	}
+	request := m.AppsClient.RESTClient().Patch(types.PatchType("application/apply-patch+yaml")).
+		Namespace(deployment.Namespace).
+		Resource("deployments").
+		Name(deployment.Name).
+		Param("fieldManager", m.FieldManager)
+	if m.Overwrite {
+		request = request.Param("force", "true")
+	}
+	result := &appsv1.Deployment{}
+	err = request.Body(data).Do().Into(result)
+	return result, err
+}
+
+// validateDeployment submits the deployment to the API server with server-side dry run, so the deployment is
+// validated, defaulted and checked by the admission plugins without being persisted.
+func (m *MigrateOptions) validateDeployment(deployment *appsv1.Deployment, replace bool) error {
+	key := deployment.Namespace + "/" + deployment.Name
+	m.progress("validating", key, fmt.Sprintf("validating deployment %q using server-side dry run ...", m.color.Blue(key)))
+	request := m.AppsClient.RESTClient().Post().Namespace(deployment.Namespace).Resource("deployments")
+	if replace {
+		request = m.AppsClient.RESTClient().Put().Namespace(deployment.Namespace).Resource("deployments").Name(deployment.Name)
+	}
+	if err := request.Param("dryRun", "All").Body(deployment).Do().Error(); err != nil {
+		return fmt.Errorf("deployment %q failed the server-side validation: %v", key, err)
+	}
+	return nil
+}
+
+// checkServerDryRun returns an error when the API server does not support server-side dry run. Older API
+// servers ignore the dry run parameter and would persist the validated deployment.
+func (m *MigrateOptions) checkServerDryRun() error {
+	data, err := m.CoreClient.RESTClient().Get().AbsPath("/version").Do().Raw()
+	if err != nil {
+		return fmt.Errorf("unable to get the server version to check the server-side dry run support: %v", err)
+	}
+	info := kversion.Info{}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return fmt.Errorf("unable to parse the server version: %v", err)
+	}
+	if !supportsServerDryRun(info) {
+		return fmt.Errorf("--validate requires server-side dry run, which is not supported by the server version %s (kubernetes 1.13 or newer is required)", info.GitVersion)
+	}
+	return nil
+}
+
+// supportsServerDryRun returns true when the server version has the server-side dry run enabled, which
+// is kubernetes 1.13 or newer. Some distributions suffix the minor version, e.g. "13+".
+func supportsServerDryRun(info kversion.Info) bool {
+	notDigit := func(r rune) bool { return !unicode.IsDigit(r) }
+	major, err := strconv.Atoi(strings.TrimRightFunc(info.Major, notDigit))
+	if err != nil {
+		return false
+	}
+	minor, err := strconv.Atoi(strings.TrimRightFunc(info.Minor, notDigit))
+	if err != nil {
+		return false
+	}
+	return major > 1 || major == 1 && minor >= 13
+}
+
+// outputObjects writes the resulting objects into the output directory and prints them in the output format,
+// when requested.
+func (m *MigrateOptions) outputObjects(key string, objects ...kruntime.Object) error {
+	m.result.addObjects(objects...)
+	if m.StripClusterFields {
+		var err error
+		if objects, err = stripClusterFields(objects...); err != nil {
+			return err
+		}
+	}
+	if len(m.OutputDir) > 0 {
+		paths, err := writeObjects(m.OutputDir, m.Overwrite, objects...)
+		for _, path := range paths {
+			m.progress("written", key, fmt.Sprintf("wrote %q", path))
+		}
+		m.written.add(paths...)
+		if err != nil {
+			return err
+		}
+	}
+	switch m.OutputFormat {
+	case "yaml", "json":
+		return printObjects(m.Output, m.OutputFormat, objects...)
+	case "name":
+		return printObjectNames(m.Output, objects...)
+	}
+	return nil
+}
+
+// migrationContext returns the context of the migration, the tests migrating without a deadline get the background context.
+func (m *MigrateOptions) migrationContext() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
+}
+
+// pollImmediateUntil checks the condition immediately and then every poll interval until it is met, it fails
+// or the migration deadline expires.
+func (m *MigrateOptions) pollImmediateUntil(condition wait.ConditionFunc) error {
+	done, err := condition()
+	if err == nil && !done {
+		err = wait.PollUntil(pollInterval, condition, m.migrationContext().Done())
+	}
+	if err != nil && m.migrationContext().Err() != nil {
+		// The requests canceled by the expired deadline fail with the context error
+		return wait.ErrWaitTimeout
+	}
+	return err
+}
+
+// waitForDeployment waits until all the deployment replicas are updated and available or until the deployment
+// fails to progress.
+func (m *MigrateOptions) waitForDeployment(deployment *appsv1.Deployment) error {
+	m.progress("waiting", deployment.Namespace+"/"+deployment.Name, fmt.Sprintf("waiting up to %s for deployment %q to become available ...", m.Timeout, m.color.Blue(deployment.Namespace+"/"+deployment.Name)))
+	current := deployment
+	err := m.pollImmediateUntil(func() (bool, error) {
+		var err error
+		current, err = m.AppsClient.Deployments(deployment.Namespace).Get(deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if current.Generation > current.Status.ObservedGeneration {
+			return false, nil
+		}
+		for _, condition := range current.Status.Conditions {
+			if condition.Type == appsv1.DeploymentProgressing && condition.Status == corev1.ConditionFalse {
+				return false, fmt.Errorf("deployment %q failed to progress: %s", deployment.Namespace+"/"+deployment.Name, condition.Message)
+			}
+		}
+		replicas := int32(1)
+		if current.Spec.Replicas != nil {
+			replicas = *current.Spec.Replicas
+		}
+		return current.Status.UpdatedReplicas == replicas && current.Status.AvailableReplicas == replicas && current.Status.Replicas == replicas, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timeout waiting for deployment %q to become available (%d of %d replicas available)", deployment.Namespace+"/"+deployment.Name,
+			current.Status.AvailableReplicas, current.Status.Replicas)
+	}
+	if err != nil {
+		return err
+	}
+	m.progress("available", deployment.Namespace+"/"+deployment.Name, fmt.Sprintf("deployment %q is available (%d replicas)", m.color.Blue(deployment.Namespace+"/"+deployment.Name), current.Status.AvailableReplicas))
+	return nil
+}
+
+// createHookJobs creates the jobs converted from the deployment config lifecycle hooks. The jobs created by
+// a previous migration of the deployment config already ran and are skipped, so they are not returned.
+func (m *MigrateOptions) createHookJobs(jobs []*batchv1.Job) ([]*batchv1.Job, error) {
+	result := []*batchv1.Job{}
+	for _, job := range jobs {
+		key := job.Namespace + "/" + job.Labels[converter.DeploymentConfigNameLabel]
+		m.progress("creating-hook", key, fmt.Sprintf("creating %s lifecycle hook job %q ...", job.Labels[converter.HookStageLabel], m.color.Blue(job.Namespace+"/"+job.Name)))
+		newJob, err := m.BatchClient.Jobs(job.Namespace).Create(job)
+		if errors.IsAlreadyExists(err) {
+			existing, err := m.BatchClient.Jobs(job.Namespace).Get(job.Name, metav1.GetOptions{})
+			if err != nil {
+				return result, err
+			}
+			if err := checkMigratedFrom("job", existing, job.Annotations[converter.SourceDeploymentConfigAnnotation]); err != nil {
+				return result, err
+			}
+			m.progress("creating-hook", key, fmt.Sprintf("%s lifecycle hook job %q already exists, skipping", job.Labels[converter.HookStageLabel], m.color.Blue(job.Namespace+"/"+job.Name)))
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		result = append(result, newJob)
+	}
+	return result, nil
+}
+
+// checkMigratedFrom returns an error when the existing object was not created by a previous migration of the
+// deployment config, so the objects created by the migration are never confused with the user objects.
+func checkMigratedFrom(kind string, existing metav1.Object, dcName string) error {
+	if existing.GetAnnotations()[converter.SourceDeploymentConfigAnnotation] != dcName {
+		return fmt.Errorf("%s %q already exists and was not created by the migration of deployment config %q", kind,
+			existing.GetNamespace()+"/"+existing.GetName(), existing.GetNamespace()+"/"+dcName)
+	}
+	return nil
+}
+
+// updateGeneratedHorizontalPodAutoscaler replaces the horizontal pod autoscaler generated by a previous migration
+// of the deployment config with the generated horizontal pod autoscaler. It returns the previous and the updated
+// horizontal pod autoscaler.
+func (m *MigrateOptions) updateGeneratedHorizontalPodAutoscaler(dc *osappsv1.DeploymentConfig, hpa *autoscalingv1.HorizontalPodAutoscaler) (*autoscalingv1.HorizontalPodAutoscaler, *autoscalingv1.HorizontalPodAutoscaler, error) {
+	existing, err := m.AutoscalingClient.HorizontalPodAutoscalers(hpa.Namespace).Get(hpa.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkMigratedFrom("horizontal pod autoscaler", existing, dc.Name); err != nil {
+		return nil, nil, err
+	}
+	m.progress("creating-hpa", dc.Namespace+"/"+dc.Name, fmt.Sprintf("horizontal pod autoscaler %q already exists, updating it ...", m.color.Blue(hpa.Namespace+"/"+hpa.Name)))
+	updated := hpa.DeepCopy()
+	updated.ResourceVersion = existing.ResourceVersion
+	result, err := m.AutoscalingClient.HorizontalPodAutoscalers(hpa.Namespace).Update(updated)
+	return existing, result, err
+}
+
+// waitForHookJob waits until the lifecycle hook job completes. A failed job fails the migration, unless the
+// hook failure policy ignores the failures.
+func (m *MigrateOptions) waitForHookJob(job *batchv1.Job) error {
+	key := job.Namespace + "/" + job.Name
+	stage := job.Labels[converter.HookStageLabel]
+	dcKey := job.Namespace + "/" + job.Labels[converter.DeploymentConfigNameLabel]
+	m.progress("waiting-hook", dcKey, fmt.Sprintf("waiting up to %s for %s lifecycle hook job %q to complete ...", m.Timeout, stage, m.color.Blue(key)))
+	err := m.pollImmediateUntil(func() (bool, error) {
+		current, err := m.BatchClient.Jobs(job.Namespace).Get(job.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, condition := range current.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchv1.JobComplete:
+				return true, nil
+			case batchv1.JobFailed:
+				return false, fmt.Errorf("%s lifecycle hook job %q failed: %s", stage, key, condition.Message)
+			}
+		}
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		err = fmt.Errorf("timeout waiting for %s lifecycle hook job %q to complete", stage, key)
+	}
+	if err != nil && job.Annotations[converter.HookFailurePolicyAnnotation] == string(osappsv1.LifecycleHookFailurePolicyIgnore) {
+		m.warning("hook-failed", dcKey, fmt.Sprintf("%v, ignored by the hook failure policy", err))
+		return nil
+	}
+	return err
+}
+
+// deleteJobActions returns the rollback actions deleting the lifecycle hook jobs created by the migration.
+func (m *MigrateOptions) deleteJobActions(jobs []*batchv1.Job) []rollbackAction {
+	result := []rollbackAction{}
+	for _, job := range jobs {
+		createdJob := job
+		result = append(result, rollbackAction{
+			description: fmt.Sprintf("deleting lifecycle hook job %q", createdJob.Namespace+"/"+createdJob.Name),
+			revert: func() error {
+				return ignoreNotFound(m.BatchClient.Jobs(createdJob.Namespace).Delete(createdJob.Name, deleteOptions()))
+			},
+		})
+	}
+	return result
+}
+
+// restorePause reverts the paused state and the original paused annotation of the deployment config paused
+// by the migration to the original deployment config.
+func (m *MigrateOptions) restorePause(dc, original *osappsv1.DeploymentConfig) error {
+	_, err := m.updateDeploymentConfig(dc, func(dc *osappsv1.DeploymentConfig) {
+		dc.Spec.Paused = original.Spec.Paused
+		if value, ok := original.Annotations[converter.OriginalPausedAnnotation]; ok {
+			dc.Annotations[converter.OriginalPausedAnnotation] = value
+			return
+		}
+		delete(dc.Annotations, converter.OriginalPausedAnnotation)
+	})
+	return err
+}
+
+// updateDeploymentConfig applies the change to the deployment config and updates it. When the deployment config
+// was modified concurrently, the change is applied to the current deployment config and the update is retried.
+func (m *MigrateOptions) updateDeploymentConfig(dc *osappsv1.DeploymentConfig, change func(*osappsv1.DeploymentConfig)) (*osappsv1.DeploymentConfig, error) {
+	current := dc.DeepCopy()
+	var result *osappsv1.DeploymentConfig
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		change(current)
+		updated, err := m.OsAppsClient.DeploymentConfigs(current.Namespace).Update(current)
+		if errors.IsConflict(err) {
+			latest, getErr := m.OsAppsClient.DeploymentConfigs(current.Namespace).Get(current.Name, metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+			current = latest
+		}
+		result = updated
+		return err
+	})
+	return result, err
+}
+
+// printReport prints the migration report of the named deployment config in the configured report format.
+func (m *MigrateOptions) printReport(name string, report *converter.Report) {
+	if m.ReportFormat == "json" {
+		data, err := json.Marshal(report)
+		if err != nil {
+			m.warning("report", name, fmt.Sprintf("unable to print migration report: %v", err))
+			return
+		}
+		fmt.Fprintln(m.logOutput(), string(data))
+		return
+	}
+	m.progress("report", name, fmt.Sprintf("migration report for deployment config %q:", m.color.Blue(report.DeploymentConfig)))
+	for _, item := range report.Converted {
+		m.progress("report", name, fmt.Sprintf("  %s %s", m.color.Green("converted:"), item))
+	}
+	for _, item := range report.Approximated {
+		m.progress("report", name, fmt.Sprintf("  %s %s", m.color.Brown("approximated:"), item))
+	}
+	for _, item := range report.Dropped {
+		m.progress("report", name, fmt.Sprintf("  %s %s", m.color.Red("dropped:"), item))
+	}
+}
+
+// findHorizontalPodAutoscalers returns the horizontal pod autoscalers that scale the deployment config.
+func (m *MigrateOptions) findHorizontalPodAutoscalers(dc *osappsv1.DeploymentConfig) ([]autoscalingv1.HorizontalPodAutoscaler, error) {
+	// Deployment configs read from files have no related cluster objects
+	if m.offline() {
+		return nil, nil
+	}
+	hpas, err := m.AutoscalingClient.HorizontalPodAutoscalers(dc.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	result := []autoscalingv1.HorizontalPodAutoscaler{}
+	for _, hpa := range hpas.Items {
+		if hpa.Spec.ScaleTargetRef.Kind == "DeploymentConfig" && hpa.Spec.ScaleTargetRef.Name == dc.Name {
+			result = append(result, hpa)
+		}
+	}
+	return result, nil
+}
+
+// retargetHorizontalPodAutoscaler points the horizontal pod autoscaler scale target to the deployment and
+// records the original scale target in the original scale target annotation.
+func (m *MigrateOptions) retargetHorizontalPodAutoscaler(hpa *autoscalingv1.HorizontalPodAutoscaler, deployment *appsv1.Deployment) error {
+	m.progress("repointing-hpa", deployment.Namespace+"/"+deployment.Name, fmt.Sprintf("repointing horizontal pod autoscaler %q to deployment %q ...", m.color.Blue(hpa.Namespace+"/"+hpa.Name),
+		m.color.Blue(deployment.Namespace+"/"+deployment.Name)))
+	originalRef, err := json.Marshal(hpa.Spec.ScaleTargetRef)
+	if err != nil {
+		return err
+	}
+	return m.updateHorizontalPodAutoscaler(hpa, func(hpa *autoscalingv1.HorizontalPodAutoscaler) {
+		hpa.Spec.ScaleTargetRef = autoscalingv1.CrossVersionObjectReference{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "Deployment",
+			Name:       deployment.Name,
+		}
+		if hpa.Annotations == nil {
+			hpa.Annotations = map[string]string{}
+		}
+		hpa.Annotations[converter.OriginalScaleTargetAnnotation] = string(originalRef)
+	})
+}
+
+// updateHorizontalPodAutoscaler applies the change to the horizontal pod autoscaler and updates it. When the
+// horizontal pod autoscaler was modified concurrently, the change is applied to the current horizontal pod
+// autoscaler and the update is retried.
+func (m *MigrateOptions) updateHorizontalPodAutoscaler(hpa *autoscalingv1.HorizontalPodAutoscaler, change func(*autoscalingv1.HorizontalPodAutoscaler)) error {
+	current := hpa.DeepCopy()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		change(current)
+		_, err := m.AutoscalingClient.HorizontalPodAutoscalers(current.Namespace).Update(current)
+		if errors.IsConflict(err) {
+			latest, getErr := m.AutoscalingClient.HorizontalPodAutoscalers(current.Namespace).Get(current.Name, metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+			current = latest
+		}
+		return err
+	})
+}
+
+// findPodDisruptionBudgets returns the pod disruption budgets that select the deployment config pods,
+// but do not select the deployment pods. These pod disruption budgets stop protecting the application
+// once the deployment takes over.
+func (m *MigrateOptions) findPodDisruptionBudgets(dc *osappsv1.DeploymentConfig, deployment *appsv1.Deployment) ([]policyv1beta1.PodDisruptionBudget, error) {
+	// Deployment configs read from files have no related cluster objects
+	if m.offline() {
+		return nil, nil
+	}
+	pdbs, err := m.PolicyClient.PodDisruptionBudgets(dc.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	dcPodLabels := labels.Set{converter.DeploymentConfigLabel: dc.Name}
+	if dc.Spec.Template != nil {
+		dcPodLabels = labels.Merge(dc.Spec.Template.Labels, dcPodLabels)
+	}
+	result := []policyv1beta1.PodDisruptionBudget{}
+	for _, pdb := range pdbs.Items {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() {
+			continue
+		}
+		if selector.Matches(dcPodLabels) && !selector.Matches(labels.Set(deployment.Spec.Template.Labels)) {
+			result = append(result, pdb)
+		}
+	}
+	return result, nil
+}
+
+// retargetPodDisruptionBudget replaces the pod disruption budget with one selecting the deployment pods.
+// The pod disruption budget selector cannot be updated, so the pod disruption budget is recreated.
+func (m *MigrateOptions) retargetPodDisruptionBudget(pdb *policyv1beta1.PodDisruptionBudget, deployment *appsv1.Deployment) error {
+	m.progress("retargeting-pdb", deployment.Namespace+"/"+deployment.Name, fmt.Sprintf("recreating pod disruption budget %q to select deployment %q pods ...",
+		m.color.Blue(pdb.Namespace+"/"+pdb.Name), m.color.Blue(deployment.Namespace+"/"+deployment.Name)))
+	if err := m.PolicyClient.PodDisruptionBudgets(pdb.Namespace).Delete(pdb.Name, &metav1.DeleteOptions{}); err != nil {
+		return err
+	}
+	originalSelector, err := json.Marshal(pdb.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	newPDB := recreatedPodDisruptionBudget(pdb, deployment.Spec.Selector)
+	newPDB.Annotations[converter.OriginalSelectorAnnotation] = string(originalSelector)
+	_, err = m.PolicyClient.PodDisruptionBudgets(pdb.Namespace).Create(newPDB)
+	return err
+}
+
+// recreatedPodDisruptionBudget returns the copy of the pod disruption budget with the selector, without the
+// fields set by the server.
+func recreatedPodDisruptionBudget(pdb *policyv1beta1.PodDisruptionBudget, selector *metav1.LabelSelector) *policyv1beta1.PodDisruptionBudget {
+	result := &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pdb.Name,
+			Namespace:   pdb.Namespace,
+			Labels:      pdb.Labels,
+			Annotations: map[string]string{},
+		},
+		Spec: *pdb.Spec.DeepCopy(),
+	}
+	for k, v := range pdb.Annotations {
+		result.Annotations[k] = v
+	}
+	result.Spec.Selector = selector.DeepCopy()
+	return result
+}
+
+// findServices returns the services selecting the pods by the label injected by the deployment config controller.
+// These services would lose all endpoints once the deployment takes over.
+func (m *MigrateOptions) findServices(dc *osappsv1.DeploymentConfig) ([]corev1.Service, error) {
+	// Deployment configs read from files have no related cluster objects
+	if m.offline() {
+		return nil, nil
+	}
+	services, err := m.CoreClient.Services(dc.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	result := []corev1.Service{}
+	for _, service := range services.Items {
+		if service.Spec.Selector[converter.DeploymentConfigLabel] == dc.Name {
+			result = append(result, service)
+		}
+	}
+	return result, nil
+}
+
+// updateServiceSelector replaces the deployment config label in the service selector with the deployment pod labels
+// and records the original selector in the original selector annotation.
+func (m *MigrateOptions) updateServiceSelector(service *corev1.Service, dc *osappsv1.DeploymentConfig, deployment *appsv1.Deployment) error {
+	m.progress("updating-service", deployment.Namespace+"/"+deployment.Name, fmt.Sprintf("updating service %q selector to match deployment %q pods ...", m.color.Blue(service.Namespace+"/"+service.Name),
+		m.color.Blue(deployment.Namespace+"/"+deployment.Name)))
+	originalSelector, err := json.Marshal(service.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if service.Annotations == nil {
+			service.Annotations = map[string]string{}
+		}
+		service.Annotations[converter.OriginalSelectorAnnotation] = string(originalSelector)
+		delete(service.Spec.Selector, converter.DeploymentConfigLabel)
+		for k, v := range deployment.Spec.Template.Labels {
+			service.Spec.Selector[k] = v
+		}
+		_, err := m.CoreClient.Services(service.Namespace).Update(service)
+		if errors.IsConflict(err) {
+			latest, getErr := m.CoreClient.Services(service.Namespace).Get(service.Name, metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+			service = latest
+		}
+		return err
+	})
+}
+
+// listReplicationControllers returns the replication controllers managed by the deployment config.
+func (m *MigrateOptions) listReplicationControllers(dc *osappsv1.DeploymentConfig) ([]corev1.ReplicationController, error) {
+	// Deployment configs read from files have no related cluster objects and the replication controllers
+	// are not even listed when the history is skipped
+	if m.offline() || m.SkipHistory {
+		return nil, nil
+	}
+	result, err := m.findReplicationControllers(dc)
+	// Less privileged users can still migrate the deployment config, only without the rollout history
+	if errors.IsForbidden(err) && !m.RequireHistory {
+		m.warning("history-forbidden", dc.Namespace+"/"+dc.Name, fmt.Sprintf("not allowed to list replication controllers of deployment config %q, the rollout history is not migrated "+
+			"(use --require-history to fail instead): %v", m.color.Blue(dc.Namespace+"/"+dc.Name), err))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) > 0 {
+		m.progress("replication-controllers", dc.Namespace+"/"+dc.Name, fmt.Sprintf("found %d replication controllers managed by %q:", len(result),
+			m.color.Blue(dc.Namespace+"/"+dc.Name)))
+		for _, rc := range result {
+			m.progress("replication-controllers", dc.Namespace+"/"+dc.Name, fmt.Sprintf("  --> %s", m.color.Gray(rc.Name)))
+		}
+	}
+
+	return result, nil
+}
+
+// findReplicationControllers returns the replication controllers labeled with the deployment config name.
+func (m *MigrateOptions) findReplicationControllers(dc *osappsv1.DeploymentConfig) ([]corev1.ReplicationController, error) {
+	// Replication controllers of old deployment configs may only have the legacy label
+	result := []corev1.ReplicationController{}
+	found := map[string]bool{}
+	for _, label := range []string{converter.DeploymentConfigNameLabel, converter.DeploymentConfigLabel} {
+		selector := labels.SelectorFromValidatedSet(labels.Set{label: dc.Name})
+		rcs, err := m.CoreClient.ReplicationControllers(dc.Namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, err
+		}
+		for _, rc := range rcs.Items {
+			if found[rc.Name] {
+				continue
+			}
+			found[rc.Name] = true
+			result = append(result, rc)
+		}
+	}
+	return result, nil
+}
+
+// migrateReplicationControllers creates replica sets owned by the deployment for every replication
+// controller managed by the deployment config, so the deployment rollout history is preserved. The replica
+// sets created by a previous migration of the deployment config are adopted by the deployment, keeping their
+// replicas, and are not returned.
+func (m *MigrateOptions) migrateReplicationControllers(deployment *appsv1.Deployment, rcs []corev1.ReplicationController) ([]*appsv1.ReplicaSet, error) {
+	replicaSets, err := converter.MigrateHistory(deployment, rcs)
+	if err != nil {
+		return nil, err
+	}
+	result := []*appsv1.ReplicaSet{}
+	for i, rs := range replicaSets {
+		m.progress("creating-replica-set", deployment.Namespace+"/"+deployment.Name, fmt.Sprintf("creating replica set %q (revision %s) from replication controller %q ...", m.color.Blue(rs.Namespace+"/"+rs.Name),
+			rs.Annotations[converter.DeploymentRevisionAnnotation], m.color.Gray(rcs[i].Name)))
+		newReplicaSet, err := m.AppsClient.ReplicaSets(rs.Namespace).Create(rs)
+		if errors.IsAlreadyExists(err) {
+			m.progress("creating-replica-set", deployment.Namespace+"/"+deployment.Name, fmt.Sprintf("replica set %q already exists, adopting it ...", m.color.Blue(rs.Namespace+"/"+rs.Name)))
+			if err := m.adoptReplicaSet(rs); err != nil {
+				return result, err
+			}
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		result = append(result, newReplicaSet)
+	}
+	return result, nil
+}
+
+// adoptReplicaSet makes the deployment the owner of the replica set created by a previous migration of the
+// deployment config and updates its annotations. The replica set template and replicas are kept.
+func (m *MigrateOptions) adoptReplicaSet(rs *appsv1.ReplicaSet) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := m.AppsClient.ReplicaSets(rs.Namespace).Get(rs.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if err := checkMigratedFrom("replica set", existing, rs.Annotations[converter.SourceDeploymentConfigAnnotation]); err != nil {
+			return err
+		}
+		existing.OwnerReferences = rs.OwnerReferences
+		for k, v := range rs.Annotations {
+			existing.Annotations[k] = v
+		}
+		_, err = m.AppsClient.ReplicaSets(rs.Namespace).Update(existing)
+		return err
+	})
+}
+
+// scaleDownReplicationControllers scales down the running replication controllers of the deployment config,
+// recording their replicas in the original replicas annotation. It returns the replication controllers
+// scaled down, also when it fails.
+func (m *MigrateOptions) scaleDownReplicationControllers(dc *osappsv1.DeploymentConfig) ([]corev1.ReplicationController, error) {
+	rcs, err := m.findReplicationControllers(dc)
+	if errors.IsForbidden(err) {
+		m.warning("not-scaled-down", dc.Namespace+"/"+dc.Name, fmt.Sprintf("not allowed to list replication controllers of deployment config %q, scale them down manually: %v",
+			m.color.Blue(dc.Namespace+"/"+dc.Name), err))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := []corev1.ReplicationController{}
+	for _, rc := range rcs {
+		if rc.Spec.Replicas == nil || *rc.Spec.Replicas == 0 {
+			continue
+		}
+		m.progress("scaling-down", dc.Namespace+"/"+dc.Name, fmt.Sprintf("scaling down replication controller %q (%d replicas) ...", m.color.Blue(rc.Namespace+"/"+rc.Name), *rc.Spec.Replicas))
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:"%d"}},"spec":{"replicas":0}}`, converter.OriginalReplicasAnnotation, *rc.Spec.Replicas)
+		if _, err := m.CoreClient.ReplicationControllers(rc.Namespace).Patch(rc.Name, types.MergePatchType, []byte(patch)); err != nil {
+			return result, err
+		}
+		result = append(result, rc)
+	}
+	return result, nil
+}
+
+// restoreReplicationControllerReplicas scales the replication controller scaled down by the migration back
+// to its original replicas and removes the original replicas annotation.
+func (m *MigrateOptions) restoreReplicationControllerReplicas(namespace, name string, replicas int32) error {
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}},"spec":{"replicas":%d}}`, converter.OriginalReplicasAnnotation, replicas)
+	_, err := m.CoreClient.ReplicationControllers(namespace).Patch(name, types.MergePatchType, []byte(patch))
+	return err
+}
+
+func NewMigrateCommand(in io.Reader, out, errOut io.Writer) *cobra.Command {
+	options := &MigrateOptions{
+		Input:   in,
+		Output:  out,
+		ErrOut:  errOut,
+		convert: converter.Convert,
+		ConverterOptions: converter.Options{
+			Version: version,
+		},
+		color:   aurora.NewAurora(false),
+		written: &writtenFiles{},
+	}
+	options.migrateHistory = options.migrateReplicationControllers
+	options.loadClients = options.completeClients
+
+	cmd := &cobra.Command{
+		Use:   "migrate-to-deployment",
+		Short: "This command migrate your deployment config to kubernetes deployment",
+		// The deployment config names are not subcommands
+		Args: cobra.ArbitraryArgs,
+		// The errors are printed by the commands and the flag error function
+		SilenceErrors: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := options.completeStreams(cmd); err != nil {
+				fmt.Fprintf(os.Stderr, options.color.Red("ERROR:").String()+" %v\n", err)
+				os.Exit(exitCodeValidation)
+			}
+			if err := options.Validate(cmd, args); err != nil {
+				fmt.Fprintf(os.Stderr, options.color.Red("ERROR:").String()+" %v\n", err)
+				os.Exit(exitCodeValidation)
+			}
+			if err := options.Complete(cmd); err != nil {
+				fmt.Fprintf(os.Stderr, options.color.Red("ERROR:").String()+" %v\n", err)
+				os.Exit(exitCode(err))
+			}
+			if err := options.Run(); err != nil {
+				fmt.Fprintf(os.Stderr, options.color.Red("ERROR:").String()+" %v\n", err)
+				os.Exit(exitCode(err))
+			}
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&options.kubeconfig, "kubeconfig", "", "(optional) absolute path to the kubeconfig file (default: $KUBECONFIG, ~/.kube/config or in-cluster configuration)")
+
+	cmd.PersistentFlags().StringVar(&options.context, "context", "", "the name of the kubeconfig context to use")
+	cmd.PersistentFlags().StringVar(&options.impersonate, "as", "", "the user or service account (system:serviceaccount:<namespace>:<name>) to impersonate")
+	cmd.PersistentFlags().StringSliceVar(&options.impersonateGroups, "as-group", nil, "the group to impersonate, can be repeated (requires --as)")
+	cmd.PersistentFlags().StringVarP(&options.Namespace, "namespace", "n", "", "namespace to use (default: current namespace)")
+	cmd.PersistentFlags().BoolVar(&options.NoColor, "no-color", false, "disable the colored output (default: disabled when the output is not a terminal)")
+	cmd.Flags().StringVar(&options.configFile, "config", "", "the YAML file with the default flag values, like \"parallelism: 4\", the flags override it")
+	cmd.Flags().StringVarP(&options.Filename, "filename", "f", "", "migrate the deployment configs named in the file, a YAML list or a name per line in the [namespace/]name form, or deployment config manifests")
+	cmd.Flags().StringSliceVar(&options.FromFiles, "from-file", nil, "read the deployment configs from the manifest files and print the converted objects without using the cluster")
+	cmd.Flags().BoolVar(&options.All, "all", false, "migrate all deployment configs in the namespace")
+	cmd.Flags().BoolVarP(&options.AllNamespaces, "all-namespaces", "A", false, "migrate the deployment configs in all namespaces (requires permission to list deployment configs cluster-wide)")
+	cmd.Flags().StringVarP(&options.Selector, "selector", "l", "", "migrate deployment configs matching the label selector")
+	cmd.Flags().BoolVarP(&options.Yes, "yes", "y", false, "migrate without asking for confirmation")
+	cmd.Flags().BoolVar(&options.FailFast, "fail-fast", false, "stop migrating all deployment configs on the first failure")
+	cmd.Flags().IntVar(&options.Parallelism, "parallelism", 1, "the number of deployment configs to migrate concurrently")
+	cmd.Flags().BoolVar(&options.DryRun, "dry-run", false, "only print the converted deployments without mutating any cluster objects")
+	cmd.Flags().BoolVar(&options.Diff, "diff", false, "print the differences in strategy, replicas, triggers and container images between the deployment configs and the converted deployments (implies --dry-run)")
+	cmd.Flags().StringVar(&options.ReportFormat, "report-format", "text", "the format of the migration report (text or json)")
+	cmd.Flags().StringVar(&options.LogFormat, "log-format", "text", "the format of the progress messages (text or json)")
+	cmd.Flags().StringVar(&options.ResultFormat, "result-format", "", "print the migration results with the names of the created objects in the given format (json), the progress messages are printed to stderr")
+	cmd.Flags().BoolVarP(&options.Quiet, "quiet", "q", false, "only print the summary of every deployment config, the warnings and errors")
+	cmd.Flags().StringVarP(&options.OutputFormat, "output", "o", "", "print the resulting objects in the given format (yaml, json or name), or write the kustomization.yaml into --output-dir (kustomize)")
+	cmd.Flags().BoolVar(&options.StripClusterFields, "strip-cluster-fields", false, "remove the uid, resourceVersion, creationTimestamp and ownerReferences from the written and printed objects")
+	cmd.Flags().StringVar(&options.OutputDir, "output-dir", "", "write the resulting objects as separate YAML files into the directory")
+	cmd.Flags().StringVar(&options.Hooks, "hooks", "", "convert deployment config lifecycle hooks into jobs (jobs) or the pre hook into init container (init), by default the hooks are dropped")
+	cmd.Flags().BoolVar(&options.MigratePDB, "migrate-pdb", false, "recreate pod disruption budgets selecting the deployment config pods to select the deployment pods")
+	cmd.Flags().BoolVar(&options.MigrateHPA, "migrate-hpa", true, "repoint horizontal pod autoscalers scaling the deployment config to the deployment")
+	cmd.Flags().StringVar(&options.ServiceFixup, "service-fixup", "label", "how to keep services selecting the deployment config pods working: "+
+		"add the deployment config label to the deployment pods (label) or update the service selector (selector)")
+	cmd.Flags().StringVar(&options.Apply, "apply", "create", "how to create the deployment: create (or replace with --overwrite) or use server-side apply (ssa, forcing the field conflicts with --overwrite)")
+	cmd.Flags().StringVar(&options.FieldManager, "field-manager", "migrate-to-deployment", "the name of the field manager the created and updated objects are attributed to")
+	cmd.Flags().BoolVar(&options.ValidateOnServer, "validate", false, "validate the deployment using server-side dry run before pausing the deployment config (requires kubernetes 1.13 or newer)")
+	cmd.Flags().BoolVar(&options.KeepPaused, "keep-paused", false, "leave the migrated deployment paused")
+	cmd.Flags().BoolVar(&options.PauseManualRollouts, "pause-manual-rollouts", true, "leave the deployment paused when the deployment config image change triggers are not automatic")
+	cmd.Flags().BoolVar(&options.Wait, "wait", false, "wait for the deployment to become available")
+	cmd.Flags().DurationVar(&options.Timeout, "timeout", 5*time.Minute, "the maximum time the migration of a deployment config can take, including waiting for the deployment and the lifecycle hook jobs, "+
+		"the migration is rolled back when it expires")
+	// The client defaults (5 QPS, burst 10) throttle the migration of many deployment configs in parallel
+	cmd.PersistentFlags().Float32Var(&options.QPS, "qps", 50, "the maximum number of API requests per second, raise it together with --parallelism")
+	cmd.PersistentFlags().IntVar(&options.Burst, "burst", 100, "the maximum number of API requests made at once above --qps")
+	cmd.Flags().BoolVar(&options.Prune, "prune", false, "delete the deployment config once the deployment is available (requires --wait)")
+	cmd.Flags().BoolVar(&options.ReMigrate, "re-migrate", false, "migrate the deployment configs already migrated by a previous run again")
+	cmd.Flags().BoolVar(&options.VerifyImageExists, "verify-image-exists", false, "check that the resolved container images are well-formed image references and warn about floating tags")
+	cmd.Flags().BoolVar(&options.PreserveReplicasFromRC, "preserve-replicas-from-rc", false, "use the replicas of the replication controller of the latest complete rollout instead of the deployment config replicas")
+	cmd.Flags().Int32Var(&options.MaxReplicas, "max-replicas", 0, "generate a horizontal pod autoscaler scaling the deployment up to these replicas, unless the deployment config is already autoscaled")
+	cmd.Flags().Int32Var(&options.MinReplicas, "min-replicas", 0, "the min replicas of the generated horizontal pod autoscaler (default 1)")
+	cmd.Flags().Int32Var(&options.CPUTarget, "cpu-target", 0, "the target CPU utilization percentage of the generated horizontal pod autoscaler (default 80)")
+	cmd.Flags().BoolVar(&options.RequireHistory, "require-history", false, "fail when not allowed to list the replication controllers instead of migrating without the rollout history")
+	cmd.Flags().BoolVar(&options.SkipHistory, "skip-history", false, "do not migrate the replication controllers into replica sets, the deployment starts without rollout history")
+	cmd.Flags().BoolVar(&options.Overwrite, "overwrite", false, "replace the deployment and the files in the output directory if they already exist")
+	cmd.Flags().StringSliceVar(&options.ConverterOptions.KeepAnnotations, "keep-annotations", nil, "copy these internal deployment config annotations to the deployment (default stripped: "+
+		strings.Join(converter.InternalAnnotations, ", ")+")")
+	cmd.Flags().StringSliceVar(&options.ConverterOptions.StripVolumePrefixes, "strip-volumes", converter.DefaultStripVolumePrefixes,
+		"remove the pod template volumes with these name prefixes and their mounts, the service account admission injects them into every pod")
+	cmd.Flags().StringVar(&options.ConverterOptions.ImageResolution, "image-resolution", converter.ImageResolutionLastTriggered,
+		"how to resolve the image change trigger images: the last triggered image with image stream fallback (lastTriggered), "+
+			"the current image stream image (imagestream) or keep the container images (keep)")
+	cmd.Flags().StringVar(&options.ConverterOptions.ImageAutomation, "emit-image-automation", converter.ImageAutomationNone,
+		"document the automatic image change triggers as image automation annotations for the given tool (argocd or none)")
+	cmd.Flags().StringVar(&options.ConverterOptions.SelectorLabel, "selector-label", "app", "the label key used for the selector when the deployment config pod template has no labels")
+	cmd.Flags().StringVar(&options.maxSurge, "max-surge", "", "the rolling update max surge as number or percentage (default: the deployment config max surge)")
+	cmd.Flags().StringVar(&options.maxUnavailable, "max-unavailable", "", "the rolling update max unavailable as number or percentage (default: the deployment config max unavailable)")
+	cmd.Flags().IntVar(&options.replicas, "replicas", 0, "the number of deployment replicas (default: the deployment config replicas)")
+	cmd.Flags().BoolVar(&options.ConverterOptions.AllowTest, "allow-test", false, "convert test deployment configs into regular deployments")
+	cmd.Flags().BoolVar(&options.ConverterOptions.Force, "force", false, "force conversion of deployment configs using custom deployment strategy to rolling update")
+
+	cmd.AddCommand(NewVerifyCommand(options))
+	cmd.AddCommand(NewUndoCommand(options))
+
+	cmd.SetUsageFunc(func(c *cobra.Command) error {
+		fmt.Fprintf(os.Stderr, "Usage: %s [namespace/]dc/foo [namespace/]dc/bar\n", c.Name())
+		return nil
+	})
+	// The flag errors of the command and its subcommands are validation errors
+	cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		fmt.Fprintf(errOut, options.color.Red("ERROR:").String()+" %v\n", err)
+		return &validationError{err}
+	})
+
+	return cmd
 }
@@ -0,0 +1,2529 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kversion "k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes/scheme"
+	appsv1client "k8s.io/client-go/kubernetes/typed/apps/v1"
+	fakeappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1/fake"
+	fakeautoscalingv1 "k8s.io/client-go/kubernetes/typed/autoscaling/v1/fake"
+	fakebatchv1 "k8s.io/client-go/kubernetes/typed/batch/v1/fake"
+	fakecorev1 "k8s.io/client-go/kubernetes/typed/core/v1/fake"
+	fakepolicyv1beta1 "k8s.io/client-go/kubernetes/typed/policy/v1beta1/fake"
+	"k8s.io/client-go/rest"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/logrusorgru/aurora"
+	osappsv1 "github.com/openshift/api/apps/v1"
+	osscheme "github.com/openshift/client-go/apps/clientset/versioned/scheme"
+	fakeosappsv1 "github.com/openshift/client-go/apps/clientset/versioned/typed/apps/v1/fake"
+
+	"github.com/mfojtik/migrate-to-deployment/pkg/converter"
+)
+
+// newTestOptions returns the migrate options with the fake clients backed by a single object tracker holding
+// the given objects.
+func newTestOptions(t *testing.T, objects ...kruntime.Object) (*MigrateOptions, *clienttesting.Fake) {
+	testScheme := kruntime.NewScheme()
+	scheme.AddToScheme(testScheme)
+	osscheme.AddToScheme(testScheme)
+	tracker := clienttesting.NewObjectTracker(testScheme, serializer.NewCodecFactory(testScheme).UniversalDecoder())
+	for _, obj := range objects {
+		if err := tracker.Add(obj); err != nil {
+			t.Fatalf("unable to add %#v: %v", obj, err)
+		}
+	}
+	fake := &clienttesting.Fake{}
+	fake.AddReactor("patch", "*", patchReaction(tracker))
+	fake.AddReactor("*", "*", clienttesting.ObjectReaction(tracker))
+
+	options := &MigrateOptions{
+		Output:       &bytes.Buffer{},
+		ErrOut:       &bytes.Buffer{},
+		Namespace:    "demo",
+		OsAppsClient: &fakeosappsv1.FakeAppsV1{Fake: fake},
+		AppsClient:   &fakeappsv1.FakeAppsV1{Fake: fake},
+		CoreClient:   &fakecorev1.FakeCoreV1{Fake: fake},
+		BatchClient:  &fakebatchv1.FakeBatchV1{Fake: fake},
+		Timeout:      time.Second,
+		Parallelism:  1,
+		Yes:          true,
+		color:        aurora.NewAurora(false),
+		convert:      converter.Convert,
+		written:      &writtenFiles{},
+
+		AutoscalingClient:   &fakeautoscalingv1.FakeAutoscalingV1{Fake: fake},
+		PolicyClient:        &fakepolicyv1beta1.FakePolicyV1beta1{Fake: fake},
+		PauseManualRollouts: true,
+	}
+	options.migrateHistory = options.migrateReplicationControllers
+	return options, fake
+}
+
+// patchReaction applies the JSON merge patches to the objects in the tracker. The strategic merge patches
+// sent by the migration only set fields, so they are applied the same way.
+func patchReaction(tracker clienttesting.ObjectTracker) clienttesting.ReactionFunc {
+	return func(action clienttesting.Action) (bool, kruntime.Object, error) {
+		patch := action.(clienttesting.PatchAction)
+		obj, err := tracker.Get(action.GetResource(), action.GetNamespace(), patch.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		current, changes := map[string]interface{}{}, map[string]interface{}{}
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return true, nil, err
+		}
+		if err := json.Unmarshal(data, &current); err != nil {
+			return true, nil, err
+		}
+		if err := json.Unmarshal(patch.GetPatch(), &changes); err != nil {
+			return true, nil, err
+		}
+		mergePatch(current, changes)
+		if data, err = json.Marshal(current); err != nil {
+			return true, nil, err
+		}
+		patched := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(kruntime.Object)
+		if err := json.Unmarshal(data, patched); err != nil {
+			return true, nil, err
+		}
+		if err := tracker.Update(action.GetResource(), patched, action.GetNamespace()); err != nil {
+			return true, nil, err
+		}
+		return true, patched, nil
+	}
+}
+
+func mergePatch(current, changes map[string]interface{}) {
+	for key, value := range changes {
+		if value == nil {
+			delete(current, key)
+			continue
+		}
+		changed, isMap := value.(map[string]interface{})
+		existing, wasMap := current[key].(map[string]interface{})
+		if isMap && wasMap {
+			mergePatch(existing, changed)
+			continue
+		}
+		current[key] = value
+	}
+}
+
+// newTestDeploymentConfig returns the demo/web deployment config with the given latest version.
+func newTestDeploymentConfig(latestVersion int64) *osappsv1.DeploymentConfig {
+	return &osappsv1.DeploymentConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+		Spec: osappsv1.DeploymentConfigSpec{
+			Replicas: 3,
+			Strategy: osappsv1.DeploymentStrategy{Type: osappsv1.DeploymentStrategyTypeRolling},
+			Triggers: osappsv1.DeploymentTriggerPolicies{{Type: osappsv1.DeploymentTriggerOnConfigChange}},
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "web", Image: "nginx:1"}},
+				},
+			},
+		},
+		Status: osappsv1.DeploymentConfigStatus{LatestVersion: latestVersion},
+	}
+}
+
+// newTestReplicationController returns the replication controller the deployment config controller creates
+// for the given version of the deployment config. The versions before the latest one run an older image.
+func newTestReplicationController(dc *osappsv1.DeploymentConfig, version int64, replicas int32) *corev1.ReplicationController {
+	name := dc.Name + "-" + strconv.FormatInt(version, 10)
+	template := dc.Spec.Template.DeepCopy()
+	if version < dc.Status.LatestVersion {
+		template.Spec.Containers[0].Image += "-" + strconv.FormatInt(version, 10)
+	}
+	template.Labels[converter.DeploymentConfigLabel] = dc.Name
+	template.Labels["deployment"] = name
+	template.Annotations = map[string]string{
+		"openshift.io/deployment.name":              name,
+		"openshift.io/deployment-config.name":       dc.Name,
+		converter.DeploymentConfigVersionAnnotation: strconv.FormatInt(version, 10),
+	}
+	return &corev1.ReplicationController{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: dc.Namespace,
+			Labels:    map[string]string{"openshift.io/deployment-config.name": dc.Name},
+			Annotations: map[string]string{
+				converter.DeploymentConfigVersionAnnotation: strconv.FormatInt(version, 10),
+			},
+		},
+		Spec: corev1.ReplicationControllerSpec{
+			Replicas: &replicas,
+			Selector: map[string]string{"deploymentconfig": dc.Name, "deployment": name},
+			Template: template,
+		},
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dc := newTestDeploymentConfig(2)
+
+	tests := []struct {
+		name        string
+		objects     []kruntime.Object
+		options     func(*MigrateOptions)
+		expectedErr string
+		validate    func(*testing.T, *MigrateOptions, *clienttesting.Fake)
+	}{
+		{
+			name: "deployment is created, resumed and takes over the history",
+			objects: []kruntime.Object{
+				dc.DeepCopy(),
+				newTestReplicationController(dc, 1, 0),
+				newTestReplicationController(dc, 2, 3),
+			},
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				deployment, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("expected the deployment to be created: %v", err)
+				}
+				if deployment.Spec.Paused {
+					t.Errorf("expected the deployment to be resumed")
+				}
+				if deployment.Spec.Template.Spec.Containers[0].Image != "nginx:1" {
+					t.Errorf("expected the deployment config pod template, got %#v", deployment.Spec.Template)
+				}
+				dc, err := m.OsAppsClient.DeploymentConfigs("demo").Get("web", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !dc.Spec.Paused {
+					t.Errorf("expected the deployment config to be paused")
+				}
+				replicaSets, err := m.AppsClient.ReplicaSets("demo").List(metav1.ListOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				revisions := []string{}
+				for _, rs := range replicaSets.Items {
+					revisions = append(revisions, rs.Annotations[converter.DeploymentRevisionAnnotation])
+				}
+				sort.Strings(revisions)
+				if !reflect.DeepEqual(revisions, []string{"1", "2"}) {
+					t.Errorf("expected the replica sets of revisions 1 and 2, got %v", revisions)
+				}
+			},
+		},
+		{
+			name:    "deployment stays paused with --keep-paused",
+			objects: []kruntime.Object{dc.DeepCopy()},
+			options: func(m *MigrateOptions) {
+				m.KeepPaused = true
+			},
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				deployment, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !deployment.Spec.Paused {
+					t.Errorf("expected the deployment to stay paused")
+				}
+			},
+		},
+		{
+			name: "wait and prune are skipped with a warning when the deployment stays paused",
+			objects: []kruntime.Object{func() kruntime.Object {
+				dc := dc.DeepCopy()
+				dc.Spec.Paused = true
+				return dc
+			}()},
+			options: func(m *MigrateOptions) {
+				m.Wait = true
+				m.Prune = true
+			},
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				if _, err := m.OsAppsClient.DeploymentConfigs("demo").Get("web", metav1.GetOptions{}); err != nil {
+					t.Errorf("expected the deployment config to be kept: %v", err)
+				}
+				if output := m.Output.(*bytes.Buffer).String(); !strings.Contains(output, "--wait and --prune skipped") {
+					t.Errorf("expected the skipped wait and prune warning, got:\n%s", output)
+				}
+			},
+		},
+		{
+			name:    "dry run does not mutate the cluster",
+			objects: []kruntime.Object{dc.DeepCopy(), newTestReplicationController(dc, 2, 3)},
+			options: func(m *MigrateOptions) {
+				m.DryRun = true
+				m.OutputFormat = "yaml"
+			},
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				for _, action := range fake.Actions() {
+					switch action.GetVerb() {
+					case "get", "list":
+					default:
+						t.Errorf("unexpected %s %s in dry run", action.GetVerb(), action.GetResource().Resource)
+					}
+				}
+				output := m.Output.(*bytes.Buffer).String()
+				if !strings.Contains(output, "kind: Deployment") || !strings.Contains(output, "kind: ReplicaSet") {
+					t.Errorf("expected the deployment and replica set printed, got:\n%s", output)
+				}
+			},
+		},
+		{
+			name: "existing deployment is not applied over",
+			objects: []kruntime.Object{
+				dc.DeepCopy(),
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"}},
+			},
+			options: func(m *MigrateOptions) {
+				m.Apply = "ssa"
+			},
+			expectedErr: `deployment "demo/web" already exists, use --overwrite to replace it`,
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				current, err := m.OsAppsClient.DeploymentConfigs("demo").Get("web", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if current.Spec.Paused {
+					t.Errorf("expected the deployment config not to be paused")
+				}
+			},
+		},
+		{
+			name: "existing deployment is not overwritten",
+			objects: []kruntime.Object{
+				dc.DeepCopy(),
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"}},
+			},
+			expectedErr: `deployment "demo/web" already exists, use --overwrite to replace it`,
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				for _, action := range fake.Actions() {
+					switch action.GetVerb() {
+					case "get", "list":
+					default:
+						t.Errorf("unexpected %s %s", action.GetVerb(), action.GetResource().Resource)
+					}
+				}
+			},
+		},
+		{
+			name: "existing deployment is replaced with --overwrite",
+			objects: []kruntime.Object{
+				dc.DeepCopy(),
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"}},
+			},
+			options: func(m *MigrateOptions) {
+				m.Overwrite = true
+			},
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				deployment, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(deployment.Spec.Template.Spec.Containers) == 0 {
+					t.Errorf("expected the deployment replaced by the converted deployment, got %#v", deployment.Spec)
+				}
+			},
+		},
+		{
+			name: "horizontal pod autoscaler that is not repointed keeps the replicas",
+			objects: []kruntime.Object{
+				dc.DeepCopy(),
+				&autoscalingv1.HorizontalPodAutoscaler{
+					ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+					Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+						ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{APIVersion: "apps.openshift.io/v1", Kind: "DeploymentConfig", Name: "web"},
+						MaxReplicas:    5,
+					},
+				},
+			},
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				deployment, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 3 {
+					t.Errorf("expected the deployment config replicas, got %v", deployment.Spec.Replicas)
+				}
+				if output := m.Output.(*bytes.Buffer).String(); !strings.Contains(output, "is scaled by horizontal pod autoscaler") {
+					t.Errorf("expected a warning to repoint the autoscaler, got:\n%s", output)
+				}
+			},
+		},
+		{
+			name: "horizontal pod autoscaler is repointed to the deployment",
+			objects: []kruntime.Object{
+				dc.DeepCopy(),
+				&autoscalingv1.HorizontalPodAutoscaler{
+					ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+					Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+						ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{APIVersion: "apps.openshift.io/v1", Kind: "DeploymentConfig", Name: "web"},
+						MaxReplicas:    5,
+					},
+				},
+			},
+			options: func(m *MigrateOptions) {
+				m.MigrateHPA = true
+			},
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				hpa, err := m.AutoscalingClient.HorizontalPodAutoscalers("demo").Get("web", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				expected := autoscalingv1.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}
+				if hpa.Spec.ScaleTargetRef != expected {
+					t.Errorf("expected scale target %#v, got %#v", expected, hpa.Spec.ScaleTargetRef)
+				}
+				deployment, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if deployment.Spec.Replicas != nil {
+					t.Errorf("expected the autoscaled deployment replicas unset, got %d", *deployment.Spec.Replicas)
+				}
+			},
+		},
+		{
+			name: "services keep selecting the deployment pods by the deployment config label",
+			objects: []kruntime.Object{
+				dc.DeepCopy(),
+				&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+					Spec:       corev1.ServiceSpec{Selector: map[string]string{converter.DeploymentConfigLabel: "web"}},
+				},
+			},
+			options: func(m *MigrateOptions) {
+				m.ServiceFixup = "label"
+			},
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				deployment, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if deployment.Spec.Template.Labels[converter.DeploymentConfigLabel] != "web" {
+					t.Errorf("expected the deployment config label in the pod template, got %v", deployment.Spec.Template.Labels)
+				}
+			},
+		},
+		{
+			name: "service selectors are updated to the deployment pod labels",
+			objects: []kruntime.Object{
+				dc.DeepCopy(),
+				&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+					Spec:       corev1.ServiceSpec{Selector: map[string]string{converter.DeploymentConfigLabel: "web"}},
+				},
+			},
+			options: func(m *MigrateOptions) {
+				m.ServiceFixup = "selector"
+			},
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				service, err := m.CoreClient.Services("demo").Get("web", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				deployment, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if _, ok := service.Spec.Selector[converter.DeploymentConfigLabel]; ok {
+					t.Errorf("expected the deployment config label removed from the service selector, got %v", service.Spec.Selector)
+				}
+				if !labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(deployment.Spec.Template.Labels)) {
+					t.Errorf("expected the service selector %v to match the deployment pod labels %v", service.Spec.Selector, deployment.Spec.Template.Labels)
+				}
+			},
+		},
+		{
+			name: "manually rolled out deployment stays paused",
+			objects: []kruntime.Object{func() *osappsv1.DeploymentConfig {
+				dc := dc.DeepCopy()
+				dc.Spec.Triggers = osappsv1.DeploymentTriggerPolicies{{
+					Type: osappsv1.DeploymentTriggerOnImageChange,
+					ImageChangeParams: &osappsv1.DeploymentTriggerImageChangeParams{
+						ContainerNames:     []string{"web"},
+						From:               corev1.ObjectReference{Kind: "ImageStreamTag", Name: "web:latest"},
+						LastTriggeredImage: "registry/demo/web@sha256:1",
+					},
+				}}
+				return dc
+			}()},
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				deployment, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !deployment.Spec.Paused || !converter.IsManualRollout(deployment) {
+					t.Errorf("expected the paused manual rollout deployment, got paused=%v annotations=%v", deployment.Spec.Paused, deployment.Annotations)
+				}
+				if !strings.Contains(m.Output.(*bytes.Buffer).String(), "was rolled out manually") {
+					t.Errorf("expected the manual rollout message, got:\n%s", m.Output.(*bytes.Buffer).String())
+				}
+			},
+		},
+		{
+			name: "history is skipped with --skip-history",
+			objects: []kruntime.Object{
+				dc.DeepCopy(),
+				newTestReplicationController(dc, 1, 0),
+				newTestReplicationController(dc, 2, 3),
+			},
+			options: func(m *MigrateOptions) {
+				m.SkipHistory = true
+			},
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				if _, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{}); err != nil {
+					t.Fatalf("expected the deployment to be created: %v", err)
+				}
+				for _, action := range fake.Actions() {
+					if resource := action.GetResource().Resource; resource == "replicationcontrollers" || resource == "replicasets" {
+						t.Errorf("expected the replication controllers not to be touched, got %s %s", action.GetVerb(), resource)
+					}
+				}
+			},
+		},
+		{
+			name: "image change trigger in other namespace is warned about",
+			objects: []kruntime.Object{
+				func() *osappsv1.DeploymentConfig {
+					dc := dc.DeepCopy()
+					dc.Spec.Triggers = append(dc.Spec.Triggers, osappsv1.DeploymentTriggerPolicy{
+						Type: osappsv1.DeploymentTriggerOnImageChange,
+						ImageChangeParams: &osappsv1.DeploymentTriggerImageChangeParams{
+							Automatic:          true,
+							ContainerNames:     []string{"web"},
+							From:               corev1.ObjectReference{Kind: "ImageStreamTag", Name: "base:1", Namespace: "shared"},
+							LastTriggeredImage: "registry/shared/base@sha256:1",
+						},
+					})
+					return dc
+				}(),
+			},
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				expected := `image change trigger references ImageStreamTag "base:1" in namespace "shared"`
+				if output := m.Output.(*bytes.Buffer).String(); !strings.Contains(output, expected) {
+					t.Errorf("expected the cross namespace trigger warning, got:\n%s", output)
+				}
+			},
+		},
+		{
+			name: "replicas of the latest complete replication controller are preserved",
+			objects: []kruntime.Object{
+				dc.DeepCopy(),
+				func() *corev1.ReplicationController {
+					rc := newTestReplicationController(dc, 2, 5)
+					rc.Annotations[converter.DeploymentPhaseAnnotation] = "Complete"
+					return rc
+				}(),
+			},
+			options: func(m *MigrateOptions) {
+				m.PreserveReplicasFromRC = true
+			},
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				deployment, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 5 {
+					t.Errorf("expected the 5 replicas of the replication controller, got %v", deployment.Spec.Replicas)
+				}
+			},
+		},
+		{
+			name:    "horizontal pod autoscaler is generated",
+			objects: []kruntime.Object{dc.DeepCopy()},
+			options: func(m *MigrateOptions) {
+				m.MaxReplicas, m.MinReplicas, m.CPUTarget = 5, 2, 60
+			},
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				hpa, err := m.AutoscalingClient.HorizontalPodAutoscalers("demo").Get("web", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("expected the horizontal pod autoscaler to be created: %v", err)
+				}
+				if target := hpa.Spec.ScaleTargetRef; target.Kind != "Deployment" || target.Name != "web" {
+					t.Errorf("expected the autoscaler to scale the deployment, got %#v", target)
+				}
+				if *hpa.Spec.MinReplicas != 2 || hpa.Spec.MaxReplicas != 5 || *hpa.Spec.TargetCPUUtilizationPercentage != 60 {
+					t.Errorf("expected 2 to 5 replicas at 60%% CPU, got %#v", hpa.Spec)
+				}
+				if hpa.Annotations[converter.SourceDeploymentConfigAnnotation] != "web" {
+					t.Errorf("expected the provenance annotations, got %v", hpa.Annotations)
+				}
+			},
+		},
+		{
+			name: "horizontal pod autoscaler is not generated for autoscaled deployment config",
+			objects: []kruntime.Object{
+				dc.DeepCopy(),
+				&autoscalingv1.HorizontalPodAutoscaler{
+					ObjectMeta: metav1.ObjectMeta{Name: "web-scaler", Namespace: "demo"},
+					Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+						ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{APIVersion: "apps.openshift.io/v1", Kind: "DeploymentConfig", Name: "web"},
+						MaxReplicas:    3,
+					},
+				},
+			},
+			options: func(m *MigrateOptions) {
+				m.MaxReplicas = 5
+			},
+			validate: func(t *testing.T, m *MigrateOptions, fake *clienttesting.Fake) {
+				if _, err := m.AutoscalingClient.HorizontalPodAutoscalers("demo").Get("web", metav1.GetOptions{}); !errors.IsNotFound(err) {
+					t.Errorf("expected no horizontal pod autoscaler generated, got %v", err)
+				}
+				if output := m.Output.(*bytes.Buffer).String(); !strings.Contains(output, "is already autoscaled, no horizontal pod autoscaler is generated") {
+					t.Errorf("expected the already autoscaled warning, got:\n%s", output)
+				}
+			},
+		},
+		{
+			name:        "missing deployment config",
+			expectedErr: `deploymentconfigs.apps.openshift.io "web" not found`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, fake := newTestOptions(t, test.objects...)
+			if test.options != nil {
+				test.options(m)
+			}
+			err := m.migrate("demo/web")
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				if test.validate != nil {
+					test.validate(t, m, fake)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			test.validate(t, m, fake)
+		})
+	}
+}
+
+func TestReMigrate(t *testing.T) {
+	dc := newTestDeploymentConfig(2)
+	dc.Spec.Strategy.RollingParams = &osappsv1.RollingDeploymentStrategyParams{
+		Pre: &osappsv1.LifecycleHook{
+			FailurePolicy: osappsv1.LifecycleHookFailurePolicyAbort,
+			ExecNewPod:    &osappsv1.ExecNewPodHook{ContainerName: "web", Command: []string{"/migrate"}},
+		},
+	}
+	m, fake := newTestOptions(t, dc, newTestReplicationController(dc, 1, 0), newTestReplicationController(dc, 2, 3))
+	m.Hooks = "jobs"
+	m.MaxReplicas = 5
+	m.Wait = true
+	completeRollouts(fake)
+	if err := m.migrate("demo/web"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m.ReMigrate = true
+	m.MaxReplicas = 8
+	m.result = &migrationResult{}
+	if err := m.migrate("demo/web"); err != nil {
+		t.Fatalf("unexpected error migrating again: %v", err)
+	}
+
+	replicaSets, err := m.AppsClient.ReplicaSets("demo").List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(replicaSets.Items) != 2 {
+		t.Errorf("expected the 2 replica sets to be adopted, got %d", len(replicaSets.Items))
+	}
+	hpa, err := m.AutoscalingClient.HorizontalPodAutoscalers("demo").Get("web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hpa.Spec.MaxReplicas != 8 {
+		t.Errorf("expected the generated horizontal pod autoscaler updated to 8 max replicas, got %d", hpa.Spec.MaxReplicas)
+	}
+	deployment, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deployment.Spec.Paused {
+		t.Errorf("expected the deployment of the deployment config paused only by the migration to be resumed")
+	}
+}
+
+func TestRun(t *testing.T) {
+	broken := newTestDeploymentConfig(1)
+	broken.Name = "broken"
+	broken.Spec.Strategy = osappsv1.DeploymentStrategy{Type: "Unknown"}
+
+	tests := []struct {
+		name             string
+		failFast         bool
+		parallelism      int
+		expectedErr      string
+		expectedMigrated []string
+	}{
+		{
+			name:             "failure does not abort the batch",
+			expectedErr:      `deployment config "demo/broken": deployment config "demo/broken" has unknown deployment strategy "Unknown"`,
+			expectedMigrated: []string{"api", "web"},
+		},
+		{
+			name:             "parallel migration",
+			parallelism:      3,
+			expectedErr:      `deployment config "demo/broken": deployment config "demo/broken" has unknown deployment strategy "Unknown"`,
+			expectedMigrated: []string{"api", "web"},
+		},
+		{
+			name:             "fail fast",
+			failFast:         true,
+			expectedErr:      `deployment config "demo/broken" has unknown deployment strategy "Unknown"`,
+			expectedMigrated: []string{"api"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api := newTestDeploymentConfig(1)
+			api.Name = "api"
+			m, _ := newTestOptions(t, api, broken.DeepCopy(), newTestDeploymentConfig(1))
+			m.FailFast = test.failFast
+			if test.parallelism > 0 {
+				m.Parallelism = test.parallelism
+			}
+			m.DeploymentConfigKeys = []string{"demo/api", "demo/broken", "demo/web"}
+
+			err := m.Run()
+			if err == nil || err.Error() != test.expectedErr {
+				t.Fatalf("expected error %q, got %v", test.expectedErr, err)
+			}
+			migrated := []string{}
+			deployments, err := m.AppsClient.Deployments("demo").List(metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, deployment := range deployments.Items {
+				migrated = append(migrated, deployment.Name)
+			}
+			sort.Strings(migrated)
+			if !reflect.DeepEqual(migrated, test.expectedMigrated) {
+				t.Errorf("expected migrated deployment configs %v, got %v", test.expectedMigrated, migrated)
+			}
+			if !test.failFast && !strings.Contains(m.Output.(*bytes.Buffer).String(), "migrated 2 of 3 deployment configs") {
+				t.Errorf("expected the summary, got:\n%s", m.Output.(*bytes.Buffer).String())
+			}
+		})
+	}
+}
+
+func TestRunAggregatedError(t *testing.T) {
+	broken := newTestDeploymentConfig(1)
+	broken.Name = "broken"
+	broken.Spec.Strategy = osappsv1.DeploymentStrategy{Type: "Unknown"}
+	custom := newTestDeploymentConfig(1)
+	custom.Name = "custom"
+	custom.Spec.Strategy = osappsv1.DeploymentStrategy{Type: osappsv1.DeploymentStrategyTypeCustom}
+	m, _ := newTestOptions(t, broken, newTestDeploymentConfig(1), custom)
+	m.DeploymentConfigKeys = []string{"demo/broken", "demo/web", "demo/custom"}
+
+	err := m.Run()
+	aggregate, ok := err.(utilerrors.Aggregate)
+	if !ok || len(aggregate.Errors()) != 2 {
+		t.Fatalf("expected aggregated error of 2 deployment configs, got %#v", err)
+	}
+	for _, expected := range []string{`deployment config "demo/broken": `, `deployment config "demo/custom": `} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error containing %q, got %v", expected, err)
+		}
+	}
+	if _, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the deployment config between the failures migrated: %v", err)
+	}
+}
+
+func TestRunAllNamespaces(t *testing.T) {
+	broken := newTestDeploymentConfig(1)
+	broken.Name = "broken"
+	broken.Spec.Strategy = osappsv1.DeploymentStrategy{Type: "Unknown"}
+	shop := newTestDeploymentConfig(1)
+	shop.Namespace = "shop"
+	m, _ := newTestOptions(t, shop, broken, newTestDeploymentConfig(1))
+	m.AllNamespaces = true
+	if err := m.completeDeploymentConfigKeys(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := m.Run()
+	if err == nil || !strings.Contains(err.Error(), `deployment config "demo/broken"`) {
+		t.Fatalf("expected the demo/broken failure, got %v", err)
+	}
+	for _, namespace := range []string{"demo", "shop"} {
+		if _, err := m.AppsClient.Deployments(namespace).Get("web", metav1.GetOptions{}); err != nil {
+			t.Errorf("expected the deployment in namespace %q: %v", namespace, err)
+		}
+	}
+	output := m.Output.(*bytes.Buffer).String()
+	demo := strings.Index(output, `namespace "demo": migrated 1 of 2 deployment configs`)
+	shopSummary := strings.Index(output, `namespace "shop": migrated 1 of 1 deployment configs`)
+	if demo < 0 || shopSummary < demo {
+		t.Errorf("expected the namespace summaries grouped by namespace, got:\n%s", output)
+	}
+	if !strings.Contains(output, "migrated 2 of 3 deployment configs") {
+		t.Errorf("expected the summary, got:\n%s", output)
+	}
+}
+
+func TestRunKustomize(t *testing.T) {
+	api := newTestDeploymentConfig(1)
+	api.Name = "api"
+	m, _ := newTestOptions(t, api, newTestDeploymentConfig(1))
+	m.DeploymentConfigKeys = []string{"demo/api", "demo/web"}
+	m.DryRun = true
+	m.OutputFormat = "kustomize"
+	m.OutputDir = t.TempDir()
+
+	if err := m.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(m.OutputDir, "kustomization.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n- demo-api-deployment.yaml\n- demo-web-deployment.yaml\n"
+	if string(data) != expected {
+		t.Errorf("expected kustomization:\n%s\ngot:\n%s", expected, data)
+	}
+	if strings.Contains(m.Output.(*bytes.Buffer).String(), "kind: Deployment") {
+		t.Errorf("expected the objects not printed with the kustomize output")
+	}
+}
+
+func TestRunStepCounter(t *testing.T) {
+	api := newTestDeploymentConfig(1)
+	api.Name = "api"
+	m, _ := newTestOptions(t, api, newTestDeploymentConfig(1))
+	m.DeploymentConfigKeys = []string{"demo/api", "demo/web"}
+
+	if err := m.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output := m.Output.(*bytes.Buffer).String()
+	for _, expected := range []string{
+		"--> [1/2 step 1] ",
+		"--> [2/2 step 1] ",
+		`finished deployment config "demo/api" in `,
+		`finished deployment config "demo/web" in `,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected output containing %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestRunQuiet(t *testing.T) {
+	api := newTestDeploymentConfig(1)
+	api.Name = "api"
+	api.Spec.Strategy.RollingParams = &osappsv1.RollingDeploymentStrategyParams{
+		Pre: &osappsv1.LifecycleHook{ExecNewPod: &osappsv1.ExecNewPodHook{ContainerName: "web", Command: []string{"/migrate"}}},
+	}
+	m, _ := newTestOptions(t, api, newTestDeploymentConfig(1))
+	m.DeploymentConfigKeys = []string{"demo/api", "demo/web"}
+	m.Quiet = true
+
+	if err := m.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output := m.Output.(*bytes.Buffer).String()
+	for _, expected := range []string{
+		`WARNING: deployment config "demo/api" Rolling strategy lifecycle hooks cannot be represented in deployment`,
+		`finished deployment config "demo/api" in `,
+		`finished deployment config "demo/web" in `,
+		"migrated 2 of 2 deployment configs",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected output containing %q, got:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "pausing deployment config") {
+		t.Errorf("expected no progress messages, got:\n%s", output)
+	}
+}
+
+func TestRunNamespacePrefixedDeploymentConfigs(t *testing.T) {
+	shop := newTestDeploymentConfig(1)
+	shop.Namespace = "shop"
+	shop.Spec.Template.Spec.Containers[0].Image = "nginx:2"
+	m, _ := newTestOptions(t, newTestDeploymentConfig(1), shop)
+	m.DeploymentConfigKeys = []string{"demo/web", "shop/web"}
+
+	if err := m.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for namespace, image := range map[string]string{"demo": "nginx:1", "shop": "nginx:2"} {
+		deployment, err := m.AppsClient.Deployments(namespace).Get("web", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("expected the deployment in namespace %q: %v", namespace, err)
+		}
+		if deployment.Spec.Template.Spec.Containers[0].Image != image {
+			t.Errorf("expected deployment %s/web with image %s, got %s", namespace, image, deployment.Spec.Template.Spec.Containers[0].Image)
+		}
+	}
+}
+
+func TestMigrateAlreadyMigrated(t *testing.T) {
+	tests := []struct {
+		name      string
+		reMigrate bool
+	}{
+		{name: "second run is a no-op"},
+		{name: "re-migrate forces a fresh conversion", reMigrate: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, fake := newTestOptions(t, newTestDeploymentConfig(1))
+			if err := m.migrate("demo/web"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			fake.ClearActions()
+			m.ReMigrate = test.reMigrate
+
+			if err := m.migrate("demo/web"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			updated := false
+			for _, action := range fake.Actions() {
+				if action.GetResource().Resource == "deployments" && (action.GetVerb() == "update" || action.GetVerb() == "create") {
+					updated = true
+				}
+			}
+			if updated != test.reMigrate {
+				t.Errorf("expected the deployment replaced %t, got actions %v", test.reMigrate, fake.Actions())
+			}
+			output := m.Output.(*bytes.Buffer).String()
+			skipped := strings.Contains(output, `deployment config "demo/web" was already migrated to deployment "demo/web", skipping`)
+			if skipped == test.reMigrate {
+				t.Errorf("expected the already migrated warning %t, got:\n%s", !test.reMigrate, output)
+			}
+		})
+	}
+}
+
+func TestMigratePodDisruptionBudgets(t *testing.T) {
+	pdb := &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo", Labels: map[string]string{"team": "shop"}},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"deploymentconfig": "web"}},
+		},
+	}
+	unrelated := &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "demo"},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"deploymentconfig": "api"}},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		migratePDB       bool
+		expectedSelector map[string]string
+		expectedWarning  bool
+	}{
+		{
+			name:             "pod disruption budget is reported",
+			expectedSelector: map[string]string{"deploymentconfig": "web"},
+			expectedWarning:  true,
+		},
+		{
+			name:             "pod disruption budget is recreated",
+			migratePDB:       true,
+			expectedSelector: map[string]string{"app": "web"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, _ := newTestOptions(t, newTestDeploymentConfig(1), pdb.DeepCopy(), unrelated.DeepCopy())
+			m.MigratePDB = test.migratePDB
+
+			if err := m.migrate("demo/web"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			updated, err := m.PolicyClient.PodDisruptionBudgets("demo").Get("web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(updated.Spec.Selector.MatchLabels, test.expectedSelector) {
+				t.Errorf("expected selector %v, got %v", test.expectedSelector, updated.Spec.Selector.MatchLabels)
+			}
+			if updated.Labels["team"] != "shop" {
+				t.Errorf("expected the pod disruption budget labels kept, got %v", updated.Labels)
+			}
+			output := m.Output.(*bytes.Buffer).String()
+			if warned := strings.Contains(output, `pod disruption budget "demo/web" selects deployment config "demo/web" pods`); warned != test.expectedWarning {
+				t.Errorf("expected warning %t, got:\n%s", test.expectedWarning, output)
+			}
+			if strings.Contains(output, `"demo/api"`) {
+				t.Errorf("expected the unrelated pod disruption budget ignored, got:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestMigratePrune(t *testing.T) {
+	tests := []struct {
+		name        string
+		available   bool
+		expectedErr string
+	}{
+		{
+			name:      "deployment config is deleted once the deployment is available",
+			available: true,
+		},
+		{
+			name:        "deployment config is kept when the deployment is not available",
+			expectedErr: `timeout waiting for deployment "demo/web" to become available`,
+		},
+	}
+
+	defer func(interval time.Duration) { pollInterval = interval }(pollInterval)
+	pollInterval = time.Millisecond
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, fake := newTestOptions(t, newTestDeploymentConfig(1))
+			m.Wait = true
+			m.Prune = true
+			m.Timeout = 20 * time.Millisecond
+			// The deployment controller is simulated by reporting the created deployment status
+			var created *appsv1.Deployment
+			fake.PrependReactor("create", "deployments", func(action clienttesting.Action) (bool, kruntime.Object, error) {
+				created = action.(clienttesting.CreateAction).GetObject().(*appsv1.Deployment).DeepCopy()
+				return false, nil, nil
+			})
+			fake.PrependReactor("get", "deployments", func(action clienttesting.Action) (bool, kruntime.Object, error) {
+				if created == nil || !test.available {
+					return false, nil, nil
+				}
+				current := created.DeepCopy()
+				replicas := *current.Spec.Replicas
+				current.Status = appsv1.DeploymentStatus{Replicas: replicas, UpdatedReplicas: replicas, AvailableReplicas: replicas}
+				return true, current, nil
+			})
+
+			err := m.migrate("demo/web")
+			_, getErr := m.OsAppsClient.DeploymentConfigs("demo").Get("web", metav1.GetOptions{})
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				if getErr != nil {
+					t.Errorf("expected the deployment config kept: %v", getErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !errors.IsNotFound(getErr) {
+				t.Errorf("expected the deployment config deleted, got %v", getErr)
+			}
+		})
+	}
+}
+
+func TestUpdateDeploymentConfigConflict(t *testing.T) {
+	dc := newTestDeploymentConfig(1)
+	// The deployment config was scaled after it was read
+	current := dc.DeepCopy()
+	current.Spec.Replicas = 5
+	m, fake := newTestOptions(t, current)
+	conflicts := 1
+	fake.PrependReactor("update", "deploymentconfigs", func(action clienttesting.Action) (bool, kruntime.Object, error) {
+		if conflicts == 0 {
+			return false, nil, nil
+		}
+		conflicts--
+		return true, nil, errors.NewConflict(osappsv1.Resource("deploymentconfigs"), "web", fmt.Errorf("the object has been modified"))
+	})
+
+	updated, err := m.updateDeploymentConfig(dc, func(dc *osappsv1.DeploymentConfig) {
+		dc.Spec.Paused = true
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !updated.Spec.Paused || updated.Spec.Replicas != 5 {
+		t.Errorf("expected the change applied to the current deployment config, got paused=%t replicas=%d", updated.Spec.Paused, updated.Spec.Replicas)
+	}
+	if dc.Spec.Paused {
+		t.Errorf("expected the given deployment config not to be mutated")
+	}
+}
+
+func TestMigrateForbiddenReplicationControllers(t *testing.T) {
+	tests := []struct {
+		name           string
+		requireHistory bool
+		expectedErr    string
+	}{
+		{
+			name: "migrated without history",
+		},
+		{
+			name:           "history required",
+			requireHistory: true,
+			expectedErr:    `replicationcontrollers is forbidden`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dc := newTestDeploymentConfig(2)
+			m, fake := newTestOptions(t, dc, newTestReplicationController(dc, 2, 3))
+			m.RequireHistory = test.requireHistory
+			fake.PrependReactor("list", "replicationcontrollers", func(action clienttesting.Action) (bool, kruntime.Object, error) {
+				return true, nil, errors.NewForbidden(corev1.Resource("replicationcontrollers"), "", fmt.Errorf("user cannot list replication controllers"))
+			})
+
+			err := m.migrate("demo/web")
+			current, getErr := m.OsAppsClient.DeploymentConfigs("demo").Get("web", metav1.GetOptions{})
+			if getErr != nil {
+				t.Fatalf("unexpected error: %v", getErr)
+			}
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				if current.Spec.Paused {
+					t.Errorf("expected the deployment config left unpaused")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output := m.Output.(*bytes.Buffer).String(); !strings.Contains(output, "the rollout history is not migrated") {
+				t.Errorf("expected the history warning, got:\n%s", output)
+			}
+			if _, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{}); err != nil {
+				t.Errorf("expected the deployment to be created: %v", err)
+			}
+			for _, action := range fake.Actions() {
+				if action.GetVerb() == "create" && action.GetResource().Resource == "replicasets" {
+					t.Errorf("expected no replica sets, got %v", action)
+				}
+			}
+		})
+	}
+}
+
+func TestMigratePausedDeploymentConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		createErr   error
+		expectedErr string
+	}{
+		{
+			name: "deployment stays paused",
+		},
+		{
+			name:        "failed migration leaves the deployment config paused",
+			createErr:   fmt.Errorf("connection refused"),
+			expectedErr: "connection refused",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dc := newTestDeploymentConfig(1)
+			dc.Spec.Paused = true
+			m, fake := newTestOptions(t, dc)
+			if test.createErr != nil {
+				fake.PrependReactor("create", "deployments", func(action clienttesting.Action) (bool, kruntime.Object, error) {
+					return true, nil, test.createErr
+				})
+			}
+
+			err := m.migrate("demo/web")
+			updated, getErr := m.OsAppsClient.DeploymentConfigs("demo").Get("web", metav1.GetOptions{})
+			if getErr != nil {
+				t.Fatalf("unexpected error: %v", getErr)
+			}
+			if !updated.Spec.Paused {
+				t.Errorf("expected the deployment config to stay paused")
+			}
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			deployment, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !deployment.Spec.Paused {
+				t.Errorf("expected the deployment to stay paused")
+			}
+			if output := m.Output.(*bytes.Buffer).String(); !strings.Contains(output, `deployment config "demo/web" is paused, leaving deployment "demo/web" paused`) {
+				t.Errorf("expected the paused message, got:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestCompleteDeploymentConfigKeysMissing(t *testing.T) {
+	api := newTestDeploymentConfig(1)
+	api.Name = "api"
+
+	tests := []struct {
+		name        string
+		keys        []string
+		expectedErr string
+	}{
+		{
+			name: "all exist",
+			keys: []string{"demo/api", "demo/web"},
+		},
+		{
+			name:        "one missing name",
+			keys:        []string{"demo/api", "demo/wbe", "demo/web"},
+			expectedErr: "deployment configs not found: demo/wbe",
+		},
+		{
+			name:        "several missing names",
+			keys:        []string{"demo/db", "demo/web", "shop/web"},
+			expectedErr: "deployment configs not found: demo/db, shop/web",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, fake := newTestOptions(t, api, newTestDeploymentConfig(1))
+			m.DeploymentConfigKeys = test.keys
+			err := m.completeDeploymentConfigKeys()
+			if len(test.expectedErr) > 0 {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Fatalf("expected error %q, got %v", test.expectedErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, action := range fake.Actions() {
+				if action.GetVerb() != "get" {
+					t.Errorf("expected nothing paused or updated, got %s %s", action.GetVerb(), action.GetResource().Resource)
+				}
+			}
+		})
+	}
+}
+
+func TestListReplicationControllers(t *testing.T) {
+	dc := newTestDeploymentConfig(3)
+	legacy := newTestReplicationController(dc, 1, 0)
+	legacy.Labels = map[string]string{converter.DeploymentConfigLabel: "web"}
+	both := newTestReplicationController(dc, 2, 0)
+	both.Labels[converter.DeploymentConfigLabel] = "web"
+	other := newTestReplicationController(dc, 1, 0)
+	other.Name = "api-1"
+	other.Labels = map[string]string{converter.DeploymentConfigNameLabel: "api", converter.DeploymentConfigLabel: "api"}
+	m, _ := newTestOptions(t, dc, legacy, both, newTestReplicationController(dc, 3, 3), other)
+
+	rcs, err := m.listReplicationControllers(dc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := []string{}
+	for _, rc := range rcs {
+		names = append(names, rc.Name)
+	}
+	sort.Strings(names)
+	if expected := []string{"web-1", "web-2", "web-3"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected replication controllers %v, got %v", expected, names)
+	}
+}
+
+func TestCompleteDeploymentConfigKeys(t *testing.T) {
+	frontend := newTestDeploymentConfig(1)
+	frontend.Name = "frontend"
+	frontend.Labels = map[string]string{"app": "frontend"}
+	backend := newTestDeploymentConfig(1)
+	backend.Name = "backend"
+	backend.Labels = map[string]string{"app": "backend"}
+	other := newTestDeploymentConfig(1)
+	other.Name = "other"
+	other.Namespace = "other"
+	other.Labels = map[string]string{"app": "frontend"}
+
+	tests := []struct {
+		name          string
+		all           bool
+		allNamespaces bool
+		selector      string
+		expectedKeys  []string
+	}{
+		{
+			name:         "all",
+			all:          true,
+			expectedKeys: []string{"demo/backend", "demo/frontend", "demo/web"},
+		},
+		{
+			name:         "selector",
+			selector:     "app=frontend",
+			expectedKeys: []string{"demo/frontend"},
+		},
+		{
+			name:         "all matching the selector",
+			all:          true,
+			selector:     "app in (frontend,backend)",
+			expectedKeys: []string{"demo/backend", "demo/frontend"},
+		},
+		{
+			name:          "all namespaces",
+			allNamespaces: true,
+			expectedKeys:  []string{"demo/backend", "demo/frontend", "demo/web", "other/other"},
+		},
+		{
+			name:          "all namespaces matching the selector",
+			allNamespaces: true,
+			selector:      "app=frontend",
+			expectedKeys:  []string{"demo/frontend", "other/other"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, _ := newTestOptions(t, frontend, backend, other, newTestDeploymentConfig(1))
+			m.All = test.all
+			m.AllNamespaces = test.allNamespaces
+			m.Selector = test.selector
+			if err := m.completeDeploymentConfigKeys(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(m.DeploymentConfigKeys, test.expectedKeys) {
+				t.Errorf("expected deployment configs %v, got %v", test.expectedKeys, m.DeploymentConfigKeys)
+			}
+		})
+	}
+}
+
+func TestMigrateReport(t *testing.T) {
+	dc := newTestDeploymentConfig(2)
+	dc.Spec.Strategy.RollingParams = &osappsv1.RollingDeploymentStrategyParams{
+		Pre: &osappsv1.LifecycleHook{
+			FailurePolicy: osappsv1.LifecycleHookFailurePolicyAbort,
+			ExecNewPod:    &osappsv1.ExecNewPodHook{ContainerName: "web", Command: []string{"/migrate"}},
+		},
+	}
+	dc.Spec.Triggers = append(dc.Spec.Triggers, osappsv1.DeploymentTriggerPolicy{
+		Type: osappsv1.DeploymentTriggerOnImageChange,
+		ImageChangeParams: &osappsv1.DeploymentTriggerImageChangeParams{
+			Automatic:          true,
+			ContainerNames:     []string{"web"},
+			From:               corev1.ObjectReference{Kind: "ImageStreamTag", Name: "web:latest"},
+			LastTriggeredImage: "registry/demo/web@sha256:1",
+		},
+	})
+	m, _ := newTestOptions(t, dc)
+	m.ReportFormat = "json"
+
+	if err := m.migrate("demo/web"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var report *converter.Report
+	for _, line := range strings.Split(m.Output.(*bytes.Buffer).String(), "\n") {
+		if strings.HasPrefix(line, "{") {
+			report = &converter.Report{}
+			if err := json.Unmarshal([]byte(line), report); err != nil {
+				t.Fatalf("unable to decode the report %q: %v", line, err)
+			}
+		}
+	}
+	if report == nil {
+		t.Fatalf("expected the json report, got:\n%s", m.Output.(*bytes.Buffer).String())
+	}
+	if report.DeploymentConfig != "demo/web" {
+		t.Errorf("expected the report of demo/web, got %q", report.DeploymentConfig)
+	}
+	if !reflect.DeepEqual(report.Dropped, []string{"Rolling strategy lifecycle hooks"}) {
+		t.Errorf("expected the dropped lifecycle hooks, got %v", report.Dropped)
+	}
+	resolved := `ImageChange trigger from ImageStreamTag "web:latest" resolved to image "registry/demo/web@sha256:1" for container "web" (automatic image updates are lost)`
+	found := false
+	for _, item := range report.Approximated {
+		found = found || item == resolved
+	}
+	if !found {
+		t.Errorf("expected the resolved image change trigger, got %v", report.Approximated)
+	}
+}
+
+func TestMigrateJSONLog(t *testing.T) {
+	m, _ := newTestOptions(t, newTestDeploymentConfig(1))
+	m.LogFormat = "json"
+
+	if err := m.migrate("demo/web"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	events := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(m.Output.(*bytes.Buffer).String()), "\n") {
+		record := logRecord{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("expected json record, got %q: %v", line, err)
+		}
+		if record.Namespace != "demo" || record.DeploymentConfig != "web" {
+			t.Errorf("expected the record of demo/web, got %#v", record)
+		}
+		if strings.Contains(record.Message, "\x1b[") {
+			t.Errorf("expected no color sequences in %q", record.Message)
+		}
+		events = append(events, record.Event)
+	}
+	for _, event := range []string{"processing", "pausing", "creating", "resuming"} {
+		found := false
+		for _, e := range events {
+			found = found || e == event
+		}
+		if !found {
+			t.Errorf("expected the %q event, got %v", event, events)
+		}
+	}
+}
+
+func TestWaitForDeployment(t *testing.T) {
+	available := appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3}
+
+	tests := []struct {
+		name string
+		// statuses are the deployment statuses returned by the successive gets, the last one repeats.
+		statuses    []appsv1.DeploymentStatus
+		expectedErr string
+	}{
+		{
+			name: "deployment becomes available",
+			statuses: []appsv1.DeploymentStatus{
+				{},
+				{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 1},
+				{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 2},
+				available,
+			},
+		},
+		{
+			name:     "old status of the previous generation",
+			statuses: []appsv1.DeploymentStatus{{Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3}, available},
+		},
+		{
+			name: "deployment fails to progress",
+			statuses: []appsv1.DeploymentStatus{
+				{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 1},
+				{
+					ObservedGeneration: 1,
+					Replicas:           3,
+					Conditions: []appsv1.DeploymentCondition{{
+						Type:    appsv1.DeploymentProgressing,
+						Status:  corev1.ConditionFalse,
+						Message: `ReplicaSet "web-5d4b7c" has timed out progressing.`,
+					}},
+				},
+			},
+			expectedErr: `deployment "demo/web" failed to progress: ReplicaSet "web-5d4b7c" has timed out progressing.`,
+		},
+		{
+			name:        "timeout",
+			statuses:    []appsv1.DeploymentStatus{{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 1}},
+			expectedErr: `timeout waiting for deployment "demo/web" to become available (1 of 3 replicas available)`,
+		},
+	}
+
+	defer func(interval time.Duration) { pollInterval = interval }(pollInterval)
+	pollInterval = time.Millisecond
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replicas := int32(3)
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo", Generation: 1},
+				Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			}
+			m, fake := newTestOptions(t)
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			m.ctx = ctx
+			gets := 0
+			fake.PrependReactor("get", "deployments", func(action clienttesting.Action) (bool, kruntime.Object, error) {
+				current := deployment.DeepCopy()
+				current.Status = test.statuses[gets]
+				if gets < len(test.statuses)-1 {
+					gets++
+				}
+				return true, current, nil
+			})
+
+			err := m.waitForDeployment(deployment)
+			if len(test.expectedErr) > 0 {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Fatalf("expected error %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if gets != len(test.statuses)-1 {
+				t.Errorf("expected to wait for all %d statuses, got %d", len(test.statuses), gets+1)
+			}
+		})
+	}
+}
+
+func TestMigrateLifecycleHooks(t *testing.T) {
+	dc := newTestDeploymentConfig(2)
+
+	tests := []struct {
+		name          string
+		failurePolicy osappsv1.LifecycleHookFailurePolicy
+		// jobCondition is the condition the pre hook job reaches, none when empty.
+		jobCondition batchv1.JobConditionType
+		expectedErr  string
+	}{
+		{
+			name:          "pre hook job completes",
+			failurePolicy: osappsv1.LifecycleHookFailurePolicyAbort,
+			jobCondition:  batchv1.JobComplete,
+		},
+		{
+			name:          "failed pre hook job aborts the migration",
+			failurePolicy: osappsv1.LifecycleHookFailurePolicyAbort,
+			jobCondition:  batchv1.JobFailed,
+			expectedErr:   `pre lifecycle hook job "demo/web-hook-pre" failed: BackoffLimitExceeded`,
+		},
+		{
+			name:          "pre hook job timeout aborts the migration",
+			failurePolicy: osappsv1.LifecycleHookFailurePolicyAbort,
+			expectedErr:   `timeout waiting for pre lifecycle hook job "demo/web-hook-pre" to complete`,
+		},
+		{
+			name:          "failed pre hook job is ignored",
+			failurePolicy: osappsv1.LifecycleHookFailurePolicyIgnore,
+			jobCondition:  batchv1.JobFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dc := dc.DeepCopy()
+			dc.Spec.Strategy.RollingParams = &osappsv1.RollingDeploymentStrategyParams{
+				Pre: &osappsv1.LifecycleHook{
+					FailurePolicy: test.failurePolicy,
+					ExecNewPod:    &osappsv1.ExecNewPodHook{ContainerName: "web", Command: []string{"/migrate"}},
+				},
+			}
+			m, fake := newTestOptions(t, dc)
+			m.Hooks = "jobs"
+			m.Timeout = 10 * time.Millisecond
+			// The job controller is simulated by setting the job condition on create
+			fake.PrependReactor("create", "jobs", func(action clienttesting.Action) (bool, kruntime.Object, error) {
+				if len(test.jobCondition) > 0 {
+					job := action.(clienttesting.CreateAction).GetObject().(*batchv1.Job)
+					job.Status.Conditions = []batchv1.JobCondition{{Type: test.jobCondition, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"}}
+				}
+				return false, nil, nil
+			})
+
+			err := m.migrate("demo/web")
+
+			// The job created by an aborted migration is deleted by the rollback
+			var job *batchv1.Job
+			for _, action := range fake.Actions() {
+				if create, ok := action.(clienttesting.CreateAction); ok && action.GetResource().Resource == "jobs" {
+					job = create.GetObject().(*batchv1.Job)
+				}
+			}
+			if job == nil {
+				t.Fatalf("expected the pre hook job to be created")
+			}
+			if command := job.Spec.Template.Spec.Containers[0].Command; !reflect.DeepEqual(command, []string{"/migrate"}) {
+				t.Errorf("expected the hook command, got %v", command)
+			}
+			if job.Annotations[converter.SourceDeploymentConfigAnnotation] != "web" || len(job.Annotations[converter.MigratedAtAnnotation]) == 0 {
+				t.Errorf("expected the provenance annotations on the hook job, got %v", job.Annotations)
+			}
+			_, getErr := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{})
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				if getErr == nil {
+					t.Errorf("expected no deployment before the pre hook completes")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if getErr != nil {
+				t.Errorf("expected the deployment to be created: %v", getErr)
+			}
+		})
+	}
+}
+
+// completeRollouts simulates the deployment and job controllers by making the deployments available and the
+// jobs complete once they are created or updated.
+func completeRollouts(fake *clienttesting.Fake) {
+	fake.PrependReactor("*", "*", func(action clienttesting.Action) (bool, kruntime.Object, error) {
+		write, ok := action.(interface {
+			GetObject() kruntime.Object
+		})
+		if !ok {
+			return false, nil, nil
+		}
+		switch obj := write.GetObject().(type) {
+		case *appsv1.Deployment:
+			replicas := int32(1)
+			if obj.Spec.Replicas != nil {
+				replicas = *obj.Spec.Replicas
+			}
+			obj.Status = appsv1.DeploymentStatus{Replicas: replicas, UpdatedReplicas: replicas, AvailableReplicas: replicas}
+		case *batchv1.Job:
+			obj.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		}
+		return false, nil, nil
+	})
+}
+
+func TestMigrateScaleDownReplicationControllers(t *testing.T) {
+	dc := newTestDeploymentConfig(2)
+
+	tests := []struct {
+		name               string
+		wait               bool
+		expectedScaledDown bool
+	}{
+		{
+			name:               "replication controllers are scaled down once the deployment is available",
+			wait:               true,
+			expectedScaledDown: true,
+		},
+		{
+			name: "replication controllers keep running without waiting for the rollout",
+		},
+	}
+
+	defer func(interval time.Duration) { pollInterval = interval }(pollInterval)
+	pollInterval = time.Millisecond
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, fake := newTestOptions(t, dc.DeepCopy(), newTestReplicationController(dc, 1, 0), newTestReplicationController(dc, 2, 3))
+			m.Wait = test.wait
+			m.Timeout = time.Second
+			completeRollouts(fake)
+
+			if err := m.migrate("demo/web"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			rc, err := m.CoreClient.ReplicationControllers("demo").Get("web-2", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !test.expectedScaledDown {
+				if rc.Spec.Replicas == nil || *rc.Spec.Replicas != 3 {
+					t.Errorf("expected the replication controller replicas kept, got %v", rc.Spec.Replicas)
+				}
+				if output := m.Output.(*bytes.Buffer).String(); !strings.Contains(output, "replication controllers keep running next to deployment") {
+					t.Errorf("expected the not scaled down warning, got:\n%s", output)
+				}
+				return
+			}
+			if rc.Spec.Replicas == nil || *rc.Spec.Replicas != 0 {
+				t.Errorf("expected the replication controller scaled down, got %v", rc.Spec.Replicas)
+			}
+			if replicas := rc.Annotations[converter.OriginalReplicasAnnotation]; replicas != "3" {
+				t.Errorf("expected the original replicas 3 recorded, got %q", replicas)
+			}
+			rc, err = m.CoreClient.ReplicationControllers("demo").Get("web-1", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := rc.Annotations[converter.OriginalReplicasAnnotation]; ok {
+				t.Errorf("expected the inactive replication controller untouched, got %v", rc.Annotations)
+			}
+		})
+	}
+}
+
+func TestMigratePostLifecycleHook(t *testing.T) {
+	dc := newTestDeploymentConfig(2)
+	dc.Spec.Strategy.RollingParams = &osappsv1.RollingDeploymentStrategyParams{
+		Post: &osappsv1.LifecycleHook{
+			FailurePolicy: osappsv1.LifecycleHookFailurePolicyAbort,
+			ExecNewPod:    &osappsv1.ExecNewPodHook{ContainerName: "web", Command: []string{"/notify"}},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		wait            bool
+		expectedCreated bool
+	}{
+		{
+			name:            "post hook job is created after the rollout",
+			wait:            true,
+			expectedCreated: true,
+		},
+		{
+			name: "post hook job is not created without waiting for the rollout",
+		},
+	}
+
+	defer func(interval time.Duration) { pollInterval = interval }(pollInterval)
+	pollInterval = time.Millisecond
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, fake := newTestOptions(t, dc.DeepCopy())
+			m.Hooks = "jobs"
+			m.Wait = test.wait
+			m.Timeout = time.Second
+			completeRollouts(fake)
+			var rolledOut, jobCreatedBeforeRollout bool
+			fake.PrependReactor("*", "*", func(action clienttesting.Action) (bool, kruntime.Object, error) {
+				switch {
+				case action.GetVerb() == "get" && action.GetResource().Resource == "deployments":
+					rolledOut = true
+				case action.GetVerb() == "create" && action.GetResource().Resource == "jobs":
+					jobCreatedBeforeRollout = !rolledOut
+				}
+				return false, nil, nil
+			})
+
+			if err := m.migrate("demo/web"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err := m.BatchClient.Jobs("demo").Get("web-hook-post", metav1.GetOptions{})
+			if !test.expectedCreated {
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected no post hook job, got %v", err)
+				}
+				if output := m.Output.(*bytes.Buffer).String(); !strings.Contains(output, `post lifecycle hook job "demo/web-hook-post" is not created`) {
+					t.Errorf("expected the skipped post hook warning, got:\n%s", output)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected the post hook job to be created: %v", err)
+			}
+			if jobCreatedBeforeRollout {
+				t.Errorf("expected the post hook job to be created once the deployment is available")
+			}
+		})
+	}
+}
+
+func TestMigrateLifecycleHookInitContainer(t *testing.T) {
+	dc := newTestDeploymentConfig(2)
+	dc.Spec.Strategy.RollingParams = &osappsv1.RollingDeploymentStrategyParams{
+		Pre:  &osappsv1.LifecycleHook{ExecNewPod: &osappsv1.ExecNewPodHook{ContainerName: "web", Command: []string{"/migrate"}}},
+		Post: &osappsv1.LifecycleHook{ExecNewPod: &osappsv1.ExecNewPodHook{ContainerName: "web", Command: []string{"/notify"}}},
+	}
+	m, fake := newTestOptions(t, dc)
+	m.Hooks = "init"
+
+	if err := m.migrate("demo/web"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, action := range fake.Actions() {
+		if action.GetResource().Resource == "jobs" {
+			t.Errorf("expected no hook jobs, got %v", action)
+		}
+	}
+	deployment, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if initContainers := deployment.Spec.Template.Spec.InitContainers; len(initContainers) != 1 || initContainers[0].Name != "hook-pre" {
+		t.Errorf("expected the pre hook init container, got %#v", initContainers)
+	}
+	if output := m.Output.(*bytes.Buffer).String(); !strings.Contains(output, "mid and post lifecycle hooks cannot be converted to init containers") {
+		t.Errorf("expected the dropped post hook warning, got:\n%s", output)
+	}
+}
+
+// writeKubeconfig writes the kubeconfig with the given contents into the temporary directory and returns its path.
+func writeKubeconfig(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestClientConfig(t *testing.T) {
+	kubeconfig := func(server string) string {
+		return "apiVersion: v1\nkind: Config\nclusters:\n- name: cluster\n  cluster:\n    server: " + server +
+			"\ncontexts:\n- name: context\n  context:\n    cluster: cluster\ncurrent-context: context\n"
+	}
+	flagKubeconfig := writeKubeconfig(t, kubeconfig("https://flag.example.com"))
+	envKubeconfig := writeKubeconfig(t, kubeconfig("https://env.example.com"))
+
+	tests := []struct {
+		name         string
+		kubeconfig   string
+		env          map[string]string
+		expectedHost string
+		expectedErr  string
+	}{
+		{
+			name:         "explicit kubeconfig wins over $KUBECONFIG",
+			kubeconfig:   flagKubeconfig,
+			env:          map[string]string{"KUBECONFIG": envKubeconfig},
+			expectedHost: "https://flag.example.com",
+		},
+		{
+			name:         "$KUBECONFIG",
+			env:          map[string]string{"KUBECONFIG": envKubeconfig},
+			expectedHost: "https://env.example.com",
+		},
+		{
+			// the service account token is not mounted outside the cluster (assumes no ~/.kube/config)
+			name:        "in-cluster configuration outside the cluster",
+			env:         map[string]string{"KUBECONFIG": "", "KUBERNETES_SERVICE_HOST": "10.0.0.1", "KUBERNETES_SERVICE_PORT": "443"},
+			expectedErr: "invalid configuration: no configuration has been provided",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			m := &MigrateOptions{kubeconfig: test.kubeconfig}
+			config, err := m.clientConfig().ClientConfig()
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.Host != test.expectedHost {
+				t.Errorf("expected host %q, got %q", test.expectedHost, config.Host)
+			}
+		})
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	// The server never answers before the request is canceled by the migration deadline
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+	kubeconfig := writeKubeconfig(t, "apiVersion: v1\nkind: Config\nclusters:\n- name: cluster\n  cluster:\n    server: "+server.URL+
+		"\ncontexts:\n- name: context\n  context:\n    cluster: cluster\n    namespace: demo\ncurrent-context: context\n")
+
+	m := &MigrateOptions{kubeconfig: kubeconfig}
+	if err := m.completeClients(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	migrator, err := m.withContext(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	start := time.Now()
+	if _, err := migrator.OsAppsClient.DeploymentConfigs("demo").Get("web", metav1.GetOptions{}); err == nil {
+		t.Fatalf("expected the request to be canceled")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the request canceled once the deadline expired, took %s", elapsed)
+	}
+}
+
+func TestCompleteClientsRateLimit(t *testing.T) {
+	kubeconfig := writeKubeconfig(t, "apiVersion: v1\nkind: Config\nclusters:\n- name: cluster\n  cluster:\n    server: https://example.com"+
+		"\ncontexts:\n- name: context\n  context:\n    cluster: cluster\n    namespace: demo\ncurrent-context: context\n")
+	cmd := NewMigrateCommand(&bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{})
+	if qps := cmd.Flag("qps").DefValue; qps != "50" {
+		t.Errorf("expected the default qps 50, got %s", qps)
+	}
+
+	m := &MigrateOptions{kubeconfig: kubeconfig, QPS: 50, Burst: 100}
+	if err := m.completeClients(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, client := range map[string]rest.Interface{
+		"apps":    m.AppsClient.RESTClient(),
+		"osapps":  m.OsAppsClient.RESTClient(),
+		"core":    m.CoreClient.RESTClient(),
+		"batch":   m.BatchClient.RESTClient(),
+		"policy":  m.PolicyClient.RESTClient(),
+		"image":   m.ImageClient.RESTClient(),
+		"scaling": m.AutoscalingClient.RESTClient(),
+	} {
+		if qps := client.GetRateLimiter().QPS(); qps != 50 {
+			t.Errorf("expected %s client qps 50, got %v", name, qps)
+		}
+	}
+}
+
+func TestCompleteClientsImpersonation(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"kind":"DeploymentConfig","apiVersion":"apps.openshift.io/v1","metadata":{"name":"web","namespace":"demo"}}`))
+	}))
+	defer server.Close()
+	kubeconfig := writeKubeconfig(t, "apiVersion: v1\nkind: Config\nclusters:\n- name: cluster\n  cluster:\n    server: "+server.URL+
+		"\ncontexts:\n- name: context\n  context:\n    cluster: cluster\n    namespace: demo\ncurrent-context: context\n")
+
+	tests := []struct {
+		name           string
+		user           string
+		groups         []string
+		expectedGroups []string
+		expectedErr    string
+	}{
+		{
+			name:           "user and groups",
+			user:           "system:serviceaccount:demo:migrator",
+			groups:         []string{"system:serviceaccounts", "migrators"},
+			expectedGroups: []string{"system:serviceaccounts", "migrators"},
+		},
+		{
+			name: "user only",
+			user: "alice",
+		},
+		{
+			name:        "groups without user",
+			groups:      []string{"migrators"},
+			expectedErr: "--as-group requires --as",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &MigrateOptions{kubeconfig: kubeconfig, impersonate: test.user, impersonateGroups: test.groups}
+			err := m.completeClients()
+			if len(test.expectedErr) > 0 {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Fatalf("expected error %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := m.OsAppsClient.DeploymentConfigs("demo").Get("web", metav1.GetOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if user := headers.Get("Impersonate-User"); user != test.user {
+				t.Errorf("expected impersonated user %q, got %q", test.user, user)
+			}
+			if groups := headers["Impersonate-Group"]; !reflect.DeepEqual(groups, test.expectedGroups) {
+				t.Errorf("expected impersonated groups %v, got %v", test.expectedGroups, groups)
+			}
+		})
+	}
+}
+
+func TestClientConfigContext(t *testing.T) {
+	kubeconfig := writeKubeconfig(t, `apiVersion: v1
+kind: Config
+clusters:
+- name: production
+  cluster:
+    server: https://production.example.com
+- name: staging
+  cluster:
+    server: https://staging.example.com
+contexts:
+- name: production
+  context:
+    cluster: production
+    namespace: shop
+- name: staging
+  context:
+    cluster: staging
+    namespace: shop-staging
+current-context: production
+`)
+
+	tests := []struct {
+		context           string
+		expectedHost      string
+		expectedNamespace string
+	}{
+		{
+			expectedHost:      "https://production.example.com",
+			expectedNamespace: "shop",
+		},
+		{
+			context:           "staging",
+			expectedHost:      "https://staging.example.com",
+			expectedNamespace: "shop-staging",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("context "+test.context, func(t *testing.T) {
+			m := &MigrateOptions{kubeconfig: kubeconfig, context: test.context}
+			config, err := m.clientConfig().ClientConfig()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.Host != test.expectedHost {
+				t.Errorf("expected host %q, got %q", test.expectedHost, config.Host)
+			}
+			if namespace, _, _ := m.clientConfig().Namespace(); namespace != test.expectedNamespace {
+				t.Errorf("expected namespace %q, got %q", test.expectedNamespace, namespace)
+			}
+		})
+	}
+}
+
+func TestCompleteNamespace(t *testing.T) {
+	kubeconfig := writeKubeconfig(t, `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: https://cluster.example.com:6443
+contexts:
+- name: team
+  context:
+    cluster: cluster
+    namespace: team-a
+current-context: team
+`)
+
+	tests := []struct {
+		name              string
+		namespace         string
+		expectedNamespace string
+	}{
+		{
+			name:              "current context namespace",
+			expectedNamespace: "team-a",
+		},
+		{
+			name:              "explicit namespace",
+			namespace:         "demo",
+			expectedNamespace: "demo",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &MigrateOptions{kubeconfig: kubeconfig, Namespace: test.namespace}
+			if err := m.Complete(nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if m.Namespace != test.expectedNamespace {
+				t.Errorf("expected namespace %q, got %q", test.expectedNamespace, m.Namespace)
+			}
+		})
+	}
+}
+
+func TestValidateArgs(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		selector     string
+		expectedKeys []string
+		expectedErr  string
+	}{
+		{
+			name:         "flags before names",
+			args:         []string{"-n", "demo", "--dry-run", "web", "db"},
+			expectedKeys: []string{"web", "db"},
+		},
+		{
+			name:         "dc prefix",
+			args:         []string{"dc/web", "db", "-n", "demo"},
+			expectedKeys: []string{"web", "db"},
+		},
+		{
+			name:        "no names",
+			args:        []string{"-n", "demo"},
+			expectedErr: "deployment config name(s) must be specified",
+		},
+		{
+			name:        "invalid selector",
+			args:        []string{"web"},
+			selector:    "app in frontend",
+			expectedErr: `invalid label selector "app in frontend": unable to parse requirement: found 'frontend' expected: '('`,
+		},
+		{
+			name:         "namespace prefix",
+			args:         []string{"shop/dc/web", "db", "-n", "demo"},
+			expectedKeys: []string{"shop/web", "db"},
+		},
+		{
+			name:        "negative replicas",
+			args:        []string{"web", "--replicas=-1"},
+			expectedErr: "--replicas must not be negative",
+		},
+		{
+			name:        "invalid selector label",
+			args:        []string{"web", "--selector-label=app name"},
+			expectedErr: `invalid selector label "app name": name part must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyName',  or 'my.name',  or '123-abc', regex used for validation is '([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]')`,
+		},
+		{
+			name:        "invalid max surge",
+			args:        []string{"web", "--max-surge=abc"},
+			expectedErr: `invalid --max-surge: "abc" must be a non-negative number or percentage`,
+		},
+		{
+			name:        "negative max unavailable",
+			args:        []string{"web", "--max-unavailable=-1"},
+			expectedErr: `invalid --max-unavailable: "-1" must be a non-negative number or percentage`,
+		},
+		{
+			name:        "zero max surge and max unavailable",
+			args:        []string{"web", "--max-surge=0%", "--max-unavailable=0"},
+			expectedErr: "--max-surge and --max-unavailable must not both be zero, the rollout could not make progress",
+		},
+		{
+			name:        "invalid image resolution",
+			args:        []string{"web", "--image-resolution=latest"},
+			expectedErr: `unsupported image resolution "latest", must be one of: lastTriggered, imagestream, keep`,
+		},
+		{
+			name:        "invalid image automation",
+			args:        []string{"web", "--emit-image-automation=flux"},
+			expectedErr: `unsupported image automation "flux", must be one of: argocd, none`,
+		},
+		{
+			name:        "unsupported result format",
+			args:        []string{"web", "--result-format=yaml"},
+			expectedErr: `unsupported result format "yaml", must be: json`,
+		},
+		{
+			name:        "kustomize without output directory",
+			args:        []string{"web", "--output=kustomize"},
+			expectedErr: "--output=kustomize requires --output-dir",
+		},
+		{
+			name:        "unsupported output format",
+			args:        []string{"web", "--output=table"},
+			expectedErr: `unsupported output format "table", must be one of: yaml, json, name, kustomize`,
+		},
+		{
+			name:        "preserve replicas with replicas",
+			args:        []string{"web", "--preserve-replicas-from-rc", "--replicas=2"},
+			expectedErr: "--preserve-replicas-from-rc cannot be used with --replicas or --from-file",
+		},
+		{
+			name:        "require history with skip history",
+			args:        []string{"web", "--require-history", "--skip-history"},
+			expectedErr: "--require-history cannot be used with --skip-history",
+		},
+		{
+			name:        "cpu target without max replicas",
+			args:        []string{"web", "--cpu-target=60"},
+			expectedErr: "--min-replicas and --cpu-target require --max-replicas",
+		},
+		{
+			name:        "min replicas greater than max replicas",
+			args:        []string{"web", "--max-replicas=2", "--min-replicas=3"},
+			expectedErr: "--min-replicas must not be greater than --max-replicas",
+		},
+		{
+			name:        "negative max replicas",
+			args:        []string{"web", "--max-replicas=-1"},
+			expectedErr: "--max-replicas, --min-replicas and --cpu-target must not be negative",
+		},
+		{
+			name:        "prune without wait",
+			args:        []string{"web", "--prune"},
+			expectedErr: "--prune requires --wait, the deployment config is only deleted when the deployment is available",
+		},
+		{
+			name:        "malformed name",
+			args:        []string{"shop/web/db"},
+			expectedErr: `invalid deployment config name "shop/web/db"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := NewMigrateCommand(&bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{})
+			if err := cmd.ParseFlags(test.args); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if namespace, _ := cmd.Flags().GetString("namespace"); namespace != "demo" && len(test.expectedKeys) > 0 {
+				t.Errorf("expected namespace flag demo, got %q", namespace)
+			}
+			m := &MigrateOptions{Selector: test.selector, ServiceFixup: "label", ReportFormat: "text", LogFormat: "text", Parallelism: 1, Apply: "create"}
+			m.replicas, _ = cmd.Flags().GetInt("replicas")
+			m.ConverterOptions.SelectorLabel, _ = cmd.Flags().GetString("selector-label")
+			m.ConverterOptions.ImageResolution, _ = cmd.Flags().GetString("image-resolution")
+			m.ConverterOptions.ImageAutomation, _ = cmd.Flags().GetString("emit-image-automation")
+			m.maxSurge, _ = cmd.Flags().GetString("max-surge")
+			m.maxUnavailable, _ = cmd.Flags().GetString("max-unavailable")
+			m.Prune, _ = cmd.Flags().GetBool("prune")
+			m.PreserveReplicasFromRC, _ = cmd.Flags().GetBool("preserve-replicas-from-rc")
+			m.RequireHistory, _ = cmd.Flags().GetBool("require-history")
+			m.MaxReplicas, _ = cmd.Flags().GetInt32("max-replicas")
+			m.MinReplicas, _ = cmd.Flags().GetInt32("min-replicas")
+			m.CPUTarget, _ = cmd.Flags().GetInt32("cpu-target")
+			m.SkipHistory, _ = cmd.Flags().GetBool("skip-history")
+			m.OutputFormat, _ = cmd.Flags().GetString("output")
+			m.ResultFormat, _ = cmd.Flags().GetString("result-format")
+			err := m.Validate(cmd, cmd.Flags().Args())
+			if len(test.expectedErr) > 0 {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Fatalf("expected error %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(m.DeploymentConfigKeys, test.expectedKeys) {
+				t.Errorf("expected deployment configs %v, got %v", test.expectedKeys, m.DeploymentConfigKeys)
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		interactive bool
+		expectedErr string
+	}{
+		{
+			name:        "yes",
+			input:       "yes\n",
+			interactive: true,
+		},
+		{
+			name:        "no",
+			input:       "no\n",
+			interactive: true,
+			expectedErr: "migration aborted",
+		},
+		{
+			name:        "end of input",
+			interactive: true,
+			expectedErr: "migration aborted",
+		},
+		{
+			name:        "input is not a terminal",
+			input:       "yes\n",
+			expectedErr: "the input is not a terminal, use --yes to confirm the migration",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, fake := newTestOptions(t, newTestDeploymentConfig(1))
+			m.Yes = false
+			m.Input = bytes.NewBufferString(test.input)
+			m.interactive = test.interactive
+			m.DeploymentConfigKeys = []string{"demo/web"}
+
+			err := m.Run()
+			if len(test.expectedErr) > 0 {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Fatalf("expected error %q, got %v", test.expectedErr, err)
+				}
+				if len(fake.Actions()) > 0 {
+					t.Errorf("expected no actions without confirmation, got %v", fake.Actions())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			output := m.ErrOut.(*bytes.Buffer).String() + m.Output.(*bytes.Buffer).String()
+			if !strings.Contains(output, "The following deployment configs will be migrated to deployments:") || !strings.Contains(output, "  demo/web\n") {
+				t.Errorf("expected the prompt listing web, got:\n%s", output)
+			}
+			if _, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{}); err != nil {
+				t.Errorf("expected the deployment to be created: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseDeploymentConfigArg(t *testing.T) {
+	tests := []struct {
+		arg               string
+		expectedNamespace string
+		expectedName      string
+		expectedErr       string
+	}{
+		{arg: "foo", expectedName: "foo"},
+		{arg: "dc/foo", expectedName: "foo"},
+		{arg: "ns/foo", expectedNamespace: "ns", expectedName: "foo"},
+		{arg: "ns/dc/foo", expectedNamespace: "ns", expectedName: "foo"},
+		{arg: "ns/bar/foo", expectedErr: `invalid deployment config name "ns/bar/foo"`},
+		{arg: "/foo", expectedErr: `invalid deployment config name "/foo"`},
+		{arg: "dc/", expectedErr: `invalid deployment config name "dc/"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.arg, func(t *testing.T) {
+			namespace, name, err := parseDeploymentConfigArg(test.arg)
+			if len(test.expectedErr) > 0 {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Fatalf("expected error %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if namespace != test.expectedNamespace || name != test.expectedName {
+				t.Errorf("expected %q/%q, got %q/%q", test.expectedNamespace, test.expectedName, namespace, name)
+			}
+		})
+	}
+}
+
+func TestParseIntOrPercent(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    intstr.IntOrString
+		expectedErr string
+	}{
+		{value: "25%", expected: intstr.FromString("25%")},
+		{value: "3", expected: intstr.FromInt(3)},
+		{value: "-1", expectedErr: `"-1" must be a non-negative number or percentage`},
+		{value: "%", expectedErr: `"%" must be a non-negative number or percentage`},
+		{value: "abc", expectedErr: `"abc" must be a non-negative number or percentage`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			value, err := parseIntOrPercent(test.value)
+			if len(test.expectedErr) > 0 {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Fatalf("expected error %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if value != test.expected {
+				t.Errorf("expected %s, got %s", test.expected.String(), value.String())
+			}
+		})
+	}
+}
+
+func TestMigrateValidateOnServer(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing bool
+		// status is the status code of the dry run request.
+		status         int
+		expectedMethod string
+		expectedPath   string
+		expectedErr    string
+	}{
+		{
+			name:           "create",
+			status:         http.StatusCreated,
+			expectedMethod: "POST",
+			expectedPath:   "/apis/apps/v1/namespaces/demo/deployments",
+		},
+		{
+			name:           "replace",
+			existing:       true,
+			status:         http.StatusOK,
+			expectedMethod: "PUT",
+			expectedPath:   "/apis/apps/v1/namespaces/demo/deployments/web",
+		},
+		{
+			name:           "rejected by the server",
+			status:         http.StatusUnprocessableEntity,
+			expectedMethod: "POST",
+			expectedPath:   "/apis/apps/v1/namespaces/demo/deployments",
+			expectedErr:    `deployment "demo/web" failed the server-side validation: `,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// requests are the mutating requests, the first one is the dry run
+			var requests []*http.Request
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if r.Method == "GET" {
+					if !test.existing {
+						w.WriteHeader(http.StatusNotFound)
+						w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+						return
+					}
+					w.Write([]byte(`{"kind":"Deployment","apiVersion":"apps/v1","metadata":{"name":"web","namespace":"demo","resourceVersion":"1"}}`))
+					return
+				}
+				requests = append(requests, r)
+				w.WriteHeader(test.status)
+				if test.status == http.StatusUnprocessableEntity {
+					w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","message":"denied by webhook","reason":"Invalid","code":422}`))
+					return
+				}
+				w.Write([]byte(`{"kind":"Deployment","apiVersion":"apps/v1","metadata":{"name":"web","namespace":"demo"}}`))
+			}))
+			defer server.Close()
+
+			m, fake := newTestOptions(t, newTestDeploymentConfig(1))
+			m.ValidateOnServer = true
+			m.Overwrite = test.existing
+			appsClient, err := appsv1client.NewForConfig(&rest.Config{Host: server.URL})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			m.AppsClient = appsClient
+
+			err = m.migrate("demo/web")
+			if len(requests) == 0 {
+				t.Fatalf("expected the dry run request")
+			}
+			request := requests[0]
+			if request.Method != test.expectedMethod || request.URL.Path != test.expectedPath || request.URL.Query().Get("dryRun") != "All" {
+				t.Errorf("expected %s %s?dryRun=All, got %s %s", test.expectedMethod, test.expectedPath, request.Method, request.URL.RequestURI())
+			}
+			if len(test.expectedErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if len(requests) != 1 {
+				t.Errorf("expected only the dry run request, got %d requests", len(requests))
+			}
+			if err == nil || !strings.Contains(err.Error(), test.expectedErr) || !strings.Contains(err.Error(), "denied by webhook") {
+				t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+			}
+			for _, action := range fake.Actions() {
+				if action.GetVerb() != "get" && action.GetVerb() != "list" {
+					t.Errorf("expected the deployment config not to be mutated, got %s %s", action.GetVerb(), action.GetResource().Resource)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateServerSideApply(t *testing.T) {
+	var patches []*http.Request
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case "GET":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+		case "PATCH":
+			body := map[string]interface{}{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("unable to decode the patch: %v", err)
+			}
+			patches = append(patches, r)
+			bodies = append(bodies, body)
+			w.Write([]byte(`{"kind":"Deployment","apiVersion":"apps/v1","metadata":{"name":"web","namespace":"demo"}}`))
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	m, _ := newTestOptions(t, newTestDeploymentConfig(1))
+	m.Apply = "ssa"
+	m.FieldManager = "migrate-to-deployment"
+	appsClient, err := appsv1client.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m.AppsClient = appsClient
+
+	if err := m.migrate("demo/web"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(patches) == 0 {
+		t.Fatalf("expected the apply patch")
+	}
+	apply := patches[0]
+	if contentType := apply.Header.Get("Content-Type"); contentType != "application/apply-patch+yaml" {
+		t.Errorf("expected the apply patch type, got %q", contentType)
+	}
+	if apply.URL.Path != "/apis/apps/v1/namespaces/demo/deployments/web" || apply.URL.Query().Get("fieldManager") != "migrate-to-deployment" {
+		t.Errorf("expected the apply of demo/web with the field manager, got %s", apply.URL.RequestURI())
+	}
+	if force := apply.URL.Query().Get("force"); len(force) > 0 {
+		t.Errorf("expected the conflicts not to be forced without --overwrite, got force=%s", force)
+	}
+	if bodies[0]["apiVersion"] != "apps/v1" || bodies[0]["kind"] != "Deployment" {
+		t.Errorf("expected the applied deployment with apiVersion and kind, got %v %v", bodies[0]["apiVersion"], bodies[0]["kind"])
+	}
+}
+
+func TestSupportsServerDryRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     kversion.Info
+		expected bool
+	}{
+		{name: "openshift 3.9", info: kversion.Info{Major: "1", Minor: "9"}},
+		{name: "kubernetes 1.12", info: kversion.Info{Major: "1", Minor: "12"}},
+		{name: "kubernetes 1.13", info: kversion.Info{Major: "1", Minor: "13"}, expected: true},
+		{name: "suffixed minor version", info: kversion.Info{Major: "1", Minor: "25+"}, expected: true},
+		{name: "unknown version", info: kversion.Info{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if supported := supportsServerDryRun(test.info); supported != test.expected {
+				t.Errorf("expected %t for %#v, got %t", test.expected, test.info, supported)
+			}
+		})
+	}
+}
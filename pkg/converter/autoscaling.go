@@ -0,0 +1,30 @@
+package converter
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HorizontalPodAutoscaler returns a new horizontal pod autoscaler scaling the deployment between the min and
+// max replicas. The nil min replicas and CPU target keep the Kubernetes defaults (1 replica and 80% of the
+// requested CPU).
+func HorizontalPodAutoscaler(deployment *appsv1.Deployment, minReplicas *int32, maxReplicas int32, cpuTarget *int32) *autoscalingv1.HorizontalPodAutoscaler {
+	return &autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        deployment.Name,
+			Namespace:   deployment.Namespace,
+			Annotations: ProvenanceAnnotations(deployment),
+		},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{
+				APIVersion: appsv1.SchemeGroupVersion.String(),
+				Kind:       "Deployment",
+				Name:       deployment.Name,
+			},
+			MinReplicas:                    minReplicas,
+			MaxReplicas:                    maxReplicas,
+			TargetCPUUtilizationPercentage: cpuTarget,
+		},
+	}
+}
@@ -1 +1,413 @@
 package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	osappsv1 "github.com/openshift/api/apps/v1"
+)
+
+const (
+	// OriginalTriggersAnnotation records the trigger types the deployment config had before the
+	// conversion. Deployments roll out on pod template change natively, so the ConfigChange trigger
+	// behavior is preserved.
+	OriginalTriggersAnnotation = "migrate-to-deployment/original-trigger"
+
+	// SourceDeploymentConfigAnnotation is set on the deployments and replica sets created by the migration
+	// and holds the name of the deployment config they were converted from.
+	SourceDeploymentConfigAnnotation = "migrate-to-deployment/source-dc"
+
+	// MigratedAtAnnotation is set on the objects created by the migration and holds the time of the migration.
+	MigratedAtAnnotation = "migrate-to-deployment/migrated-at"
+
+	// MigratedByVersionAnnotation is set on the objects created by the migration and holds the version
+	// of the migration tool.
+	MigratedByVersionAnnotation = "migrate-to-deployment/version"
+
+	// ManualRolloutAnnotation is set on deployments converted from deployment configs with image change
+	// triggers that are not automatic, which means the rollouts were started manually.
+	ManualRolloutAnnotation = "migrate-to-deployment/manual-rollout"
+
+	// CustomStrategyAnnotation is set on deployments forcibly converted from deployment configs with custom
+	// strategy and holds the custom strategy parameters (image, command and environment) as JSON, so the
+	// custom deployment logic that has to be re-implemented is documented.
+	CustomStrategyAnnotation = "migrate-to-deployment/custom-strategy"
+
+	// OriginalPausedAnnotation is set on the deployment configs paused by the migration and holds whether the
+	// deployment config was paused before the migration, so the migration can be re-run or undone.
+	OriginalPausedAnnotation = "migrate-to-deployment/original-paused"
+
+	// OriginalScaleTargetAnnotation is set on the horizontal pod autoscalers repointed by the migration and
+	// holds the original scale target as JSON, so the migration can be undone.
+	OriginalScaleTargetAnnotation = "migrate-to-deployment/original-scale-target"
+
+	// OriginalSelectorAnnotation is set on the services and pod disruption budgets updated by the migration
+	// and holds their original selector as JSON, so the migration can be undone.
+	OriginalSelectorAnnotation = "migrate-to-deployment/original-selector"
+
+	// DeploymentConfigNameLabel is set on the replication controllers created by the deployment config
+	// controller and holds the deployment config name.
+	// TODO: Move this to openshift/api
+	DeploymentConfigNameLabel = "openshift.io/deployment-config.name"
+
+	// DeploymentConfigLabel is injected into the pods by the deployment config controller and
+	// holds the deployment config name.
+	// TODO: Move this to openshift/api
+	DeploymentConfigLabel = "deploymentconfig"
+
+	// DeprecatedAffinityAnnotation is the alpha annotation that held the pod affinity before the
+	// affinity field was added to the pod spec.
+	DeprecatedAffinityAnnotation = "scheduler.alpha.kubernetes.io/affinity"
+)
+
+// InternalAnnotations are the deployment config annotations managed by the deployment config controller
+// or the client tooling, which are meaningless on the deployment and are not copied.
+var InternalAnnotations = []string{
+	DeploymentConfigVersionAnnotation,
+	"openshift.io/deployment-config.name",
+	"openshift.io/deployment.phase",
+	"openshift.io/deployment.cancelled",
+	"openshift.io/deployment.status-reason",
+	"openshift.io/encoded-deployment-config",
+	"kubectl.kubernetes.io/last-applied-configuration",
+}
+
+// Options holds the options for converting deployment configs.
+type Options struct {
+	// Force converts deployment configs that cannot be converted automatically
+	// (eg. custom strategy) into rolling update deployments.
+	Force bool
+
+	// Autoscaled leaves the deployment replicas unset, as the replicas are managed by
+	// a horizontal pod autoscaler.
+	Autoscaled bool
+
+	// SelectorLabel is the key of the label synthesized for the selector of deployment configs with
+	// no pod template labels, "app" when not set.
+	SelectorLabel string
+
+	// MaxSurge and MaxUnavailable, when set, override the rolling update parameters inherited from
+	// the deployment config.
+	MaxSurge       *intstr.IntOrString
+	MaxUnavailable *intstr.IntOrString
+
+	// Replicas, when set, overrides the replicas inherited from the deployment config.
+	Replicas *int32
+
+	// ImageResolver resolves the images for image change triggers that have no
+	// last triggered image recorded.
+	ImageResolver ImageResolver
+
+	// ImageResolution controls how the image change trigger images are resolved (lastTriggered, imagestream
+	// or keep), lastTriggered when not set.
+	ImageResolution string
+
+	// ImageAutomation is the image automation the annotations documenting the automatic image change
+	// triggers are written for (argocd or none), none when not set.
+	ImageAutomation string
+
+	// Report, when set, records how the individual parts of the deployment config were converted.
+	Report *Report
+
+	// AllowTest converts test deployment configs into regular deployments.
+	AllowTest bool
+
+	// Version is the version of the migration tool recorded in the provenance annotations.
+	Version string
+
+	// MigratedAt is the time of the migration recorded in the provenance annotations, the current
+	// time is used when not set.
+	MigratedAt time.Time
+
+	// KeepAnnotations are the internal annotations that are copied to the deployment anyway.
+	KeepAnnotations []string
+
+	// StripVolumePrefixes are the name prefixes of the injected volumes removed from the pod template,
+	// see DefaultStripVolumePrefixes. No volumes are removed when not set.
+	StripVolumePrefixes []string
+}
+
+// Convert converts the given deployment config into a new Kubernetes deployment.
+// The deployment config is not mutated.
+func Convert(dc *osappsv1.DeploymentConfig, opts Options) (*appsv1.Deployment, error) {
+	if dc.Spec.Template == nil {
+		return nil, fmt.Errorf("deployment config %q has no pod template", dc.Namespace+"/"+dc.Name)
+	}
+
+	// Test deployment configs scale down to zero after every rollout, deployments cannot do that
+	if dc.Spec.Test {
+		if !opts.AllowTest {
+			return nil, fmt.Errorf("deployment config %q is a test deployment config which scales down after every rollout, "+
+				"deployments have no equivalent, allow converting it to a regular deployment explicitly", dc.Namespace+"/"+dc.Name)
+		}
+		opts.Report.Approximatedf("test deployment config as regular deployment (pods are not scaled down after rollout)")
+	}
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        dc.Name,
+			Namespace:   dc.Namespace,
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		},
+	}
+
+	for k, v := range dc.Labels {
+		deployment.Labels[k] = v
+	}
+	for k, v := range dc.Annotations {
+		if hasString(InternalAnnotations, k) && !hasString(opts.KeepAnnotations, k) {
+			opts.Report.Droppedf("internal annotation %q", k)
+			continue
+		}
+		deployment.Annotations[k] = v
+	}
+	migratedAt := opts.MigratedAt
+	if migratedAt.IsZero() {
+		migratedAt = time.Now()
+	}
+	deployment.Annotations[SourceDeploymentConfigAnnotation] = dc.Name
+	deployment.Annotations[MigratedAtAnnotation] = migratedAt.UTC().Format(time.RFC3339)
+	deployment.Annotations[MigratedByVersionAnnotation] = opts.Version
+
+	// The pod template type is shared by deployment configs and deployments, so the whole template
+	// is copied verbatim, including the container environment (env and envFrom with all valueFrom sources).
+	deployment.Spec.Template = *dc.Spec.Template.DeepCopy()
+	opts.Report.Convertedf("pod template")
+	stripInjectedVolumes(&deployment.Spec.Template, opts.StripVolumePrefixes, opts.Report)
+
+	// The scheduling constraints (nodeSelector, tolerations and affinity) are part of the pod spec and
+	// are copied with the template. The alpha affinity annotation is copied too, but it has no effect.
+	if HasDeprecatedAffinity(dc) {
+		opts.Report.Droppedf("%q annotation (not honored by the scheduler)", DeprecatedAffinityAnnotation)
+	}
+
+	// The service account and image pull secrets are copied with the template, only the deprecated
+	// service account field is moved into serviceAccountName. An implicit default service account
+	// stays empty.
+	if podSpec := &deployment.Spec.Template.Spec; len(podSpec.ServiceAccountName) == 0 {
+		podSpec.ServiceAccountName = podSpec.DeprecatedServiceAccount
+	}
+
+	// Deployments require explicit selector matching the pod template labels
+	if len(deployment.Spec.Template.Labels) == 0 {
+		selectorLabel := opts.SelectorLabel
+		if len(selectorLabel) == 0 {
+			selectorLabel = "app"
+		}
+		deployment.Spec.Template.Labels = map[string]string{selectorLabel: dc.Name}
+		opts.Report.Approximatedf("pod template has no labels, selector %q synthesized", selectorLabel+"="+dc.Name)
+	}
+	selector := map[string]string{}
+	for k, v := range deployment.Spec.Template.Labels {
+		selector[k] = v
+	}
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: selector}
+
+	deployment.Spec.MinReadySeconds = dc.Spec.MinReadySeconds
+	// Unset revision history limit keeps the Kubernetes default
+	if dc.Spec.RevisionHistoryLimit != nil {
+		limit := *dc.Spec.RevisionHistoryLimit
+		deployment.Spec.RevisionHistoryLimit = &limit
+	}
+
+	switch {
+	case opts.Replicas != nil:
+		replicas := *opts.Replicas
+		deployment.Spec.Replicas = &replicas
+		opts.Report.Approximatedf("replicas overridden to %d (deployment config has %d)", replicas, dc.Spec.Replicas)
+	case !opts.Autoscaled:
+		replicas := dc.Spec.Replicas
+		deployment.Spec.Replicas = &replicas
+		opts.Report.Convertedf("replicas (%d)", replicas)
+	default:
+		opts.Report.Approximatedf("replicas left unset as they are managed by horizontal pod autoscaler")
+	}
+
+	if err := resolveTriggerImages(dc, &deployment.Spec.Template, opts); err != nil {
+		return nil, err
+	}
+
+	if opts.ImageAutomation == ImageAutomationArgoCD {
+		setArgoCDImageUpdaterAnnotations(dc, deployment, opts)
+	}
+
+	if err := convertStrategy(dc, deployment, opts); err != nil {
+		return nil, err
+	}
+
+	for _, trigger := range dc.Spec.Triggers {
+		if trigger.Type == osappsv1.DeploymentTriggerOnConfigChange {
+			opts.Report.Convertedf("ConfigChange trigger (deployments roll out on pod template change)")
+		}
+	}
+	if triggers := triggerTypes(dc); len(triggers) > 0 {
+		deployment.Annotations[OriginalTriggersAnnotation] = strings.Join(triggers, ",")
+	}
+	if hasManualImageChangeTrigger(dc) {
+		deployment.Annotations[ManualRolloutAnnotation] = "true"
+		opts.Report.Approximatedf("non-automatic ImageChange trigger as %q annotation (the deployment rolls out on every pod template change)",
+			ManualRolloutAnnotation)
+	}
+
+	return deployment, nil
+}
+
+// HasDeprecatedAffinity returns true when the deployment config pod template expresses the affinity
+// using the deprecated alpha annotation.
+func HasDeprecatedAffinity(dc *osappsv1.DeploymentConfig) bool {
+	if dc.Spec.Template == nil {
+		return false
+	}
+	_, ok := dc.Spec.Template.Annotations[DeprecatedAffinityAnnotation]
+	return ok
+}
+
+// ProvenanceAnnotations returns the annotations recording the deployment config the deployment was
+// migrated from, when and by which version of the migration tool. The annotations are set on all
+// objects created by the migration.
+func ProvenanceAnnotations(deployment *appsv1.Deployment) map[string]string {
+	result := map[string]string{}
+	for _, key := range []string{SourceDeploymentConfigAnnotation, MigratedAtAnnotation, MigratedByVersionAnnotation} {
+		if value, ok := deployment.Annotations[key]; ok {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// IsManualRollout returns true when the deployment was converted from a deployment config that was
+// rolled out manually.
+func IsManualRollout(deployment *appsv1.Deployment) bool {
+	return deployment.Annotations[ManualRolloutAnnotation] == "true"
+}
+
+// hasManualImageChangeTrigger returns true when the deployment config has an image change trigger
+// that does not roll out automatically.
+func hasManualImageChangeTrigger(dc *osappsv1.DeploymentConfig) bool {
+	for _, trigger := range dc.Spec.Triggers {
+		if trigger.Type == osappsv1.DeploymentTriggerOnImageChange && trigger.ImageChangeParams != nil && !trigger.ImageChangeParams.Automatic {
+			return true
+		}
+	}
+	return false
+}
+
+// convertStrategy translates the deployment config strategy into the deployment strategy.
+func convertStrategy(dc *osappsv1.DeploymentConfig, deployment *appsv1.Deployment, opts Options) error {
+	switch dc.Spec.Strategy.Type {
+	// Deployment configs without explicit strategy type default to rolling.
+	case osappsv1.DeploymentStrategyTypeRolling, "":
+		deployment.Spec.Strategy = convertRollingStrategy(dc.Spec.Strategy.RollingParams)
+		opts.Report.Convertedf("Rolling strategy as RollingUpdate")
+		if params := dc.Spec.Strategy.RollingParams; params != nil {
+			convertTimeoutSeconds(params.TimeoutSeconds, deployment, "rolling", opts.Report)
+			if params.IntervalSeconds != nil || params.UpdatePeriodSeconds != nil {
+				opts.Report.Droppedf("rolling intervalSeconds and updatePeriodSeconds")
+			}
+		}
+	case osappsv1.DeploymentStrategyTypeRecreate:
+		deployment.Spec.Strategy = appsv1.DeploymentStrategy{
+			Type: appsv1.RecreateDeploymentStrategyType,
+		}
+		opts.Report.Convertedf("Recreate strategy")
+		if params := dc.Spec.Strategy.RecreateParams; params != nil {
+			convertTimeoutSeconds(params.TimeoutSeconds, deployment, "recreate", opts.Report)
+		}
+	case osappsv1.DeploymentStrategyTypeCustom:
+		if !opts.Force {
+			image := ""
+			if params := dc.Spec.Strategy.CustomParams; params != nil {
+				image = params.Image
+			}
+			return fmt.Errorf("deployment config %q uses custom deployment strategy (image %q) which cannot be migrated automatically, "+
+				"re-implement the custom deployment logic or force the conversion to rolling update strategy", dc.Namespace+"/"+dc.Name, image)
+		}
+		deployment.Spec.Strategy = convertRollingStrategy(nil)
+		opts.Report.Approximatedf("Custom strategy forced to RollingUpdate")
+		if params := dc.Spec.Strategy.CustomParams; params != nil {
+			data, err := json.Marshal(params)
+			if err != nil {
+				return err
+			}
+			deployment.Annotations[CustomStrategyAnnotation] = string(data)
+			env := []string{}
+			for _, e := range params.Environment {
+				env = append(env, e.Name)
+			}
+			opts.Report.Droppedf("custom deployer image %q with command %q and environment [%s] (recorded in %q annotation)",
+				params.Image, strings.Join(params.Command, " "), strings.Join(env, ", "), CustomStrategyAnnotation)
+		}
+	default:
+		return fmt.Errorf("deployment config %q has unknown deployment strategy %q", dc.Namespace+"/"+dc.Name, dc.Spec.Strategy.Type)
+	}
+
+	if rollingUpdate := deployment.Spec.Strategy.RollingUpdate; rollingUpdate != nil {
+		if opts.MaxSurge != nil {
+			maxSurge := *opts.MaxSurge
+			rollingUpdate.MaxSurge = &maxSurge
+			opts.Report.Approximatedf("maxSurge overridden to %s", maxSurge.String())
+		}
+		if opts.MaxUnavailable != nil {
+			maxUnavailable := *opts.MaxUnavailable
+			rollingUpdate.MaxUnavailable = &maxUnavailable
+			opts.Report.Approximatedf("maxUnavailable overridden to %s", maxUnavailable.String())
+		}
+	}
+	return nil
+}
+
+// convertTimeoutSeconds maps the strategy timeout into the deployment progress deadline. The deployment
+// config fails the rollout after the timeout, the deployment only reports it stopped progressing.
+// Zero or unset timeout leaves the Kubernetes default progress deadline (600 seconds).
+func convertTimeoutSeconds(timeoutSeconds *int64, deployment *appsv1.Deployment, strategy string, report *Report) {
+	if timeoutSeconds == nil || *timeoutSeconds <= 0 {
+		return
+	}
+	deadline := int32(*timeoutSeconds)
+	deployment.Spec.ProgressDeadlineSeconds = &deadline
+	report.Approximatedf("%s timeoutSeconds as progressDeadlineSeconds (%d)", strategy, deadline)
+}
+
+// triggerTypes returns the unique trigger types of the deployment config in order of appearance.
+func triggerTypes(dc *osappsv1.DeploymentConfig) []string {
+	result := []string{}
+	for _, trigger := range dc.Spec.Triggers {
+		if !hasString(result, string(trigger.Type)) {
+			result = append(result, string(trigger.Type))
+		}
+	}
+	return result
+}
+
+// convertRollingStrategy converts the rolling parameters into the rolling update strategy.
+// The intervalSeconds and updatePeriodSeconds parameters have no equivalent in deployments
+// as the deployment controller does not poll.
+func convertRollingStrategy(params *osappsv1.RollingDeploymentStrategyParams) appsv1.DeploymentStrategy {
+	// These match the Kubernetes defaults for deployments.
+	maxSurge := intstr.FromString("25%")
+	maxUnavailable := intstr.FromString("25%")
+
+	if params != nil {
+		if params.MaxSurge != nil {
+			maxSurge = *params.MaxSurge
+		}
+		if params.MaxUnavailable != nil {
+			maxUnavailable = *params.MaxUnavailable
+		}
+	}
+
+	return appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxSurge:       &maxSurge,
+			MaxUnavailable: &maxUnavailable,
+		},
+	}
+}
@@ -0,0 +1,1013 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	osappsv1 "github.com/openshift/api/apps/v1"
+)
+
+// newDeploymentConfig returns a minimal deployment config with a single container.
+func newDeploymentConfig() *osappsv1.DeploymentConfig {
+	return &osappsv1.DeploymentConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "demo",
+		},
+		Spec: osappsv1.DeploymentConfigSpec{
+			Replicas: 3,
+			Strategy: osappsv1.DeploymentStrategy{Type: osappsv1.DeploymentStrategyTypeRolling},
+			Template: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"app": "web"},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "web", Image: "nginx:1"}},
+				},
+			},
+		},
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func intOrStringPtr(value intstr.IntOrString) *intstr.IntOrString {
+	return &value
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name string
+		dc   func() *osappsv1.DeploymentConfig
+		opts Options
+		// expectedErr is the part of the expected error message, no error is expected when empty.
+		expectedErr string
+		validate    func(*testing.T, *appsv1.Deployment)
+	}{
+		{
+			name: "metadata and template",
+			dc:   newDeploymentConfig,
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if deployment.Name != "web" || deployment.Namespace != "demo" {
+					t.Errorf("expected demo/web deployment, got %s/%s", deployment.Namespace, deployment.Name)
+				}
+				if source := deployment.Annotations[SourceDeploymentConfigAnnotation]; source != "web" {
+					t.Errorf("expected deployment converted from deployment config web, got %q", source)
+				}
+				if !reflect.DeepEqual(deployment.Spec.Template, *newDeploymentConfig().Spec.Template) {
+					t.Errorf("expected the deployment config template, got %#v", deployment.Spec.Template)
+				}
+			},
+		},
+		{
+			name: "recreate strategy",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Strategy = osappsv1.DeploymentStrategy{Type: osappsv1.DeploymentStrategyTypeRecreate}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if deployment.Spec.Strategy.Type != appsv1.RecreateDeploymentStrategyType || deployment.Spec.Strategy.RollingUpdate != nil {
+					t.Errorf("expected recreate strategy, got %#v", deployment.Spec.Strategy)
+				}
+			},
+		},
+		{
+			name: "custom strategy",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Strategy = osappsv1.DeploymentStrategy{
+					Type:         osappsv1.DeploymentStrategyTypeCustom,
+					CustomParams: &osappsv1.CustomDeploymentStrategyParams{Image: "deployer:1"},
+				}
+				return dc
+			},
+			expectedErr: `uses custom deployment strategy (image "deployer:1")`,
+		},
+		{
+			name: "forced custom strategy",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Strategy = osappsv1.DeploymentStrategy{
+					Type: osappsv1.DeploymentStrategyTypeCustom,
+					CustomParams: &osappsv1.CustomDeploymentStrategyParams{
+						Image:       "deployer:1",
+						Command:     []string{"/deploy"},
+						Environment: []corev1.EnvVar{{Name: "CANARY", Value: "true"}},
+					},
+				}
+				return dc
+			},
+			opts: Options{Force: true},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if deployment.Spec.Strategy.Type != appsv1.RollingUpdateDeploymentStrategyType {
+					t.Errorf("expected rolling update strategy, got %q", deployment.Spec.Strategy.Type)
+				}
+				expected := `{"image":"deployer:1","environment":[{"name":"CANARY","value":"true"}],"command":["/deploy"]}`
+				if annotation := deployment.Annotations[CustomStrategyAnnotation]; annotation != expected {
+					t.Errorf("expected the custom strategy parameters %s, got %s", expected, annotation)
+				}
+			},
+		},
+		{
+			name: "triggers",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Triggers = osappsv1.DeploymentTriggerPolicies{
+					{Type: osappsv1.DeploymentTriggerOnConfigChange},
+					{
+						Type: osappsv1.DeploymentTriggerOnImageChange,
+						ImageChangeParams: &osappsv1.DeploymentTriggerImageChangeParams{
+							Automatic:          true,
+							ContainerNames:     []string{"web"},
+							From:               corev1.ObjectReference{Kind: "ImageStreamTag", Name: "web:latest"},
+							LastTriggeredImage: "registry/demo/web@sha256:1",
+						},
+					},
+				}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if value := deployment.Annotations[OriginalTriggersAnnotation]; value != "ConfigChange,ImageChange" {
+					t.Errorf("expected ConfigChange,ImageChange original triggers, got %q", value)
+				}
+				if IsManualRollout(deployment) {
+					t.Errorf("expected no manual rollout for automatic image change trigger")
+				}
+				if image := deployment.Spec.Template.Spec.Containers[0].Image; image != "registry/demo/web@sha256:1" {
+					t.Errorf("expected the last triggered image, got %q", image)
+				}
+			},
+		},
+		{
+			name: "environment variables",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{
+					{Name: "MODE", Value: "production"},
+					{
+						Name: "PASSWORD",
+						ValueFrom: &corev1.EnvVarSource{
+							SecretKeyRef: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "db"},
+								Key:                  "password",
+							},
+						},
+					},
+				}
+				dc.Spec.Template.Spec.Containers[0].EnvFrom = []corev1.EnvFromSource{
+					{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}}},
+				}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				container := deployment.Spec.Template.Spec.Containers[0]
+				if env := container.Env; len(env) != 2 || env[0].Name != "MODE" || env[0].Value != "production" ||
+					env[1].ValueFrom == nil || env[1].ValueFrom.SecretKeyRef == nil ||
+					env[1].ValueFrom.SecretKeyRef.Name != "db" || env[1].ValueFrom.SecretKeyRef.Key != "password" {
+					t.Errorf("expected the literal and secret environment variables, got %#v", env)
+				}
+				if envFrom := container.EnvFrom; len(envFrom) != 1 || envFrom[0].ConfigMapRef == nil || envFrom[0].ConfigMapRef.Name != "settings" {
+					t.Errorf("expected the environment from the settings config map, got %#v", envFrom)
+				}
+			},
+		},
+		{
+			name: "persistent volume claim and secret volumes",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Spec.Volumes = []corev1.Volume{
+					{Name: "data", VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "web-data"}}},
+					{Name: "tls", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "web-tls", DefaultMode: int32Ptr(0400)}}},
+				}
+				dc.Spec.Template.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{
+					{Name: "data", MountPath: "/data"},
+					{Name: "tls", MountPath: "/etc/tls", ReadOnly: true},
+				}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				podSpec := deployment.Spec.Template.Spec
+				if len(podSpec.Volumes) != 2 {
+					t.Fatalf("expected the data and tls volumes, got %#v", podSpec.Volumes)
+				}
+				if claim := podSpec.Volumes[0].PersistentVolumeClaim; claim == nil || claim.ClaimName != "web-data" {
+					t.Errorf("expected the web-data persistent volume claim, got %#v", podSpec.Volumes[0])
+				}
+				if secret := podSpec.Volumes[1].Secret; secret == nil || secret.SecretName != "web-tls" || secret.DefaultMode == nil || *secret.DefaultMode != 0400 {
+					t.Errorf("expected the web-tls secret with 0400 mode, got %#v", podSpec.Volumes[1])
+				}
+				expectedMounts := []corev1.VolumeMount{
+					{Name: "data", MountPath: "/data"},
+					{Name: "tls", MountPath: "/etc/tls", ReadOnly: true},
+				}
+				if mounts := podSpec.Containers[0].VolumeMounts; !reflect.DeepEqual(mounts, expectedMounts) {
+					t.Errorf("expected volume mounts %#v, got %#v", expectedMounts, mounts)
+				}
+			},
+		},
+		{
+			name: "readiness and liveness probes",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Spec.Containers[0].ReadinessProbe = &corev1.Probe{
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromInt(8080), Scheme: corev1.URISchemeHTTP},
+					},
+					InitialDelaySeconds: 5,
+					PeriodSeconds:       10,
+				}
+				dc.Spec.Template.Spec.Containers[0].LivenessProbe = &corev1.Probe{
+					Handler: corev1.Handler{
+						Exec: &corev1.ExecAction{Command: []string{"/bin/check", "--live"}},
+					},
+					FailureThreshold: 3,
+					TimeoutSeconds:   2,
+				}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				container := deployment.Spec.Template.Spec.Containers[0]
+				expectedReadiness := &corev1.Probe{
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromInt(8080), Scheme: corev1.URISchemeHTTP},
+					},
+					InitialDelaySeconds: 5,
+					PeriodSeconds:       10,
+				}
+				if !reflect.DeepEqual(container.ReadinessProbe, expectedReadiness) {
+					t.Errorf("expected readiness probe %#v, got %#v", expectedReadiness, container.ReadinessProbe)
+				}
+				expectedLiveness := &corev1.Probe{
+					Handler: corev1.Handler{
+						Exec: &corev1.ExecAction{Command: []string{"/bin/check", "--live"}},
+					},
+					FailureThreshold: 3,
+					TimeoutSeconds:   2,
+				}
+				if !reflect.DeepEqual(container.LivenessProbe, expectedLiveness) {
+					t.Errorf("expected liveness probe %#v, got %#v", expectedLiveness, container.LivenessProbe)
+				}
+			},
+		},
+		{
+			name: "replicas",
+			dc:   newDeploymentConfig,
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 3 {
+					t.Errorf("expected 3 replicas, got %v", deployment.Spec.Replicas)
+				}
+			},
+		},
+		{
+			name: "autoscaled replicas are unset",
+			dc:   newDeploymentConfig,
+			opts: Options{Autoscaled: true},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if deployment.Spec.Replicas != nil {
+					t.Errorf("expected unset replicas, got %d", *deployment.Spec.Replicas)
+				}
+			},
+		},
+		{
+			name: "selector from the pod template labels",
+			dc:   newDeploymentConfig,
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if !reflect.DeepEqual(deployment.Spec.Selector.MatchLabels, deployment.Spec.Template.Labels) {
+					t.Errorf("expected the selector to match the pod template labels %v, got %v", deployment.Spec.Template.Labels, deployment.Spec.Selector)
+				}
+				deployment.Spec.Template.Labels["version"] = "v2"
+				if _, ok := deployment.Spec.Selector.MatchLabels["version"]; ok {
+					t.Errorf("expected the selector not to share the pod template labels map")
+				}
+			},
+		},
+		{
+			name: "selector synthesized for template without labels",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Labels = nil
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				expected := map[string]string{"app": "web"}
+				if !reflect.DeepEqual(deployment.Spec.Template.Labels, expected) || !reflect.DeepEqual(deployment.Spec.Selector.MatchLabels, expected) {
+					t.Errorf("expected app=web labels and selector, got %v and %v", deployment.Spec.Template.Labels, deployment.Spec.Selector)
+				}
+			},
+		},
+		{
+			name: "selector synthesized with custom label",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Labels = nil
+				return dc
+			},
+			opts: Options{SelectorLabel: "app.kubernetes.io/name"},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				expected := map[string]string{"app.kubernetes.io/name": "web"}
+				if !reflect.DeepEqual(deployment.Spec.Template.Labels, expected) || !reflect.DeepEqual(deployment.Spec.Selector.MatchLabels, expected) {
+					t.Errorf("expected app.kubernetes.io/name=web labels and selector, got %v and %v", deployment.Spec.Template.Labels, deployment.Spec.Selector)
+				}
+			},
+		},
+		{
+			name: "revision history limit and min ready seconds",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.RevisionHistoryLimit = int32Ptr(2)
+				dc.Spec.MinReadySeconds = 10
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if deployment.Spec.RevisionHistoryLimit == nil || *deployment.Spec.RevisionHistoryLimit != 2 {
+					t.Errorf("expected revision history limit 2, got %v", deployment.Spec.RevisionHistoryLimit)
+				}
+				if deployment.Spec.MinReadySeconds != 10 {
+					t.Errorf("expected 10 min ready seconds, got %d", deployment.Spec.MinReadySeconds)
+				}
+			},
+		},
+		{
+			name: "unset revision history limit",
+			dc:   newDeploymentConfig,
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if deployment.Spec.RevisionHistoryLimit != nil {
+					t.Errorf("expected unset revision history limit, got %d", *deployment.Spec.RevisionHistoryLimit)
+				}
+			},
+		},
+		{
+			name: "service account and image pull secrets",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Spec.ServiceAccountName = "deployer"
+				dc.Spec.Template.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "registry"}}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				podSpec := deployment.Spec.Template.Spec
+				if podSpec.ServiceAccountName != "deployer" {
+					t.Errorf("expected deployer service account, got %q", podSpec.ServiceAccountName)
+				}
+				if !reflect.DeepEqual(podSpec.ImagePullSecrets, []corev1.LocalObjectReference{{Name: "registry"}}) {
+					t.Errorf("expected the registry image pull secret, got %v", podSpec.ImagePullSecrets)
+				}
+			},
+		},
+		{
+			name: "deprecated service account",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Spec.DeprecatedServiceAccount = "builder"
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if deployment.Spec.Template.Spec.ServiceAccountName != "builder" {
+					t.Errorf("expected builder service account, got %q", deployment.Spec.Template.Spec.ServiceAccountName)
+				}
+			},
+		},
+		{
+			name: "implicit default service account",
+			dc:   newDeploymentConfig,
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if len(deployment.Spec.Template.Spec.ServiceAccountName) > 0 {
+					t.Errorf("expected no service account, got %q", deployment.Spec.Template.Spec.ServiceAccountName)
+				}
+			},
+		},
+		{
+			name: "node selector and pod anti affinity",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Spec.NodeSelector = map[string]string{"region": "primary"}
+				dc.Spec.Template.Spec.Affinity = &corev1.Affinity{
+					PodAntiAffinity: &corev1.PodAntiAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+							LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+							TopologyKey:   "kubernetes.io/hostname",
+						}},
+					},
+				}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				podSpec := deployment.Spec.Template.Spec
+				if !reflect.DeepEqual(podSpec.NodeSelector, map[string]string{"region": "primary"}) {
+					t.Errorf("expected region=primary node selector, got %v", podSpec.NodeSelector)
+				}
+				if podSpec.Affinity == nil || podSpec.Affinity.PodAntiAffinity == nil ||
+					len(podSpec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) != 1 ||
+					podSpec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0].TopologyKey != "kubernetes.io/hostname" {
+					t.Errorf("expected the hostname pod anti affinity, got %#v", podSpec.Affinity)
+				}
+			},
+		},
+		{
+			name: "resource requests and limits",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Spec.Containers[0].Resources = corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("250m"),
+						corev1.ResourceMemory: resource.MustParse("64Mi"),
+					},
+					Limits: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("1"),
+						corev1.ResourceMemory: resource.MustParse("128Mi"),
+						"nvidia.com/gpu":      resource.MustParse("1"),
+						"hugepages-2Mi":       resource.MustParse("100Mi"),
+					},
+				}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				resources := deployment.Spec.Template.Spec.Containers[0].Resources
+				for name, value := range map[corev1.ResourceName]string{corev1.ResourceCPU: "250m", corev1.ResourceMemory: "64Mi"} {
+					if quantity, ok := resources.Requests[name]; !ok || quantity.String() != value {
+						t.Errorf("expected %s request %s, got %v", name, value, resources.Requests)
+					}
+				}
+				for name, value := range map[corev1.ResourceName]string{
+					corev1.ResourceCPU:    "1",
+					corev1.ResourceMemory: "128Mi",
+					"nvidia.com/gpu":      "1",
+					"hugepages-2Mi":       "100Mi",
+				} {
+					if quantity, ok := resources.Limits[name]; !ok || quantity.String() != value {
+						t.Errorf("expected %s limit %s, got %v", name, value, resources.Limits)
+					}
+				}
+				if len(resources.Requests) != 2 || len(resources.Limits) != 4 {
+					t.Errorf("expected 2 requests and 4 limits, got %v", resources)
+				}
+			},
+		},
+		{
+			name: "labels and annotations without internal annotations",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Labels = map[string]string{"team": "a"}
+				dc.Annotations = map[string]string{
+					"description":                     "web server",
+					DeploymentConfigVersionAnnotation: "4",
+					"openshift.io/deployment.phase":   "Complete",
+				}
+				return dc
+			},
+			opts: Options{KeepAnnotations: []string{"openshift.io/deployment.phase"}},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if deployment.Labels["team"] != "a" {
+					t.Errorf("expected team label, got %v", deployment.Labels)
+				}
+				if deployment.Annotations["description"] != "web server" {
+					t.Errorf("expected description annotation, got %v", deployment.Annotations)
+				}
+				if _, ok := deployment.Annotations[DeploymentConfigVersionAnnotation]; ok {
+					t.Errorf("expected %q annotation to be stripped", DeploymentConfigVersionAnnotation)
+				}
+				if deployment.Annotations["openshift.io/deployment.phase"] != "Complete" {
+					t.Errorf("expected kept phase annotation, got %v", deployment.Annotations)
+				}
+			},
+		},
+		{
+			name: "manual image change trigger",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Triggers = osappsv1.DeploymentTriggerPolicies{{
+					Type: osappsv1.DeploymentTriggerOnImageChange,
+					ImageChangeParams: &osappsv1.DeploymentTriggerImageChangeParams{
+						ContainerNames:     []string{"web"},
+						From:               corev1.ObjectReference{Kind: "ImageStreamTag", Name: "web:latest"},
+						LastTriggeredImage: "registry/demo/web@sha256:1",
+					},
+				}}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if !IsManualRollout(deployment) || deployment.Annotations[ManualRolloutAnnotation] != "true" {
+					t.Errorf("expected the manual rollout annotation, got %v", deployment.Annotations)
+				}
+			},
+		},
+		{
+			name: "init containers",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Spec.InitContainers = []corev1.Container{{
+					Name:         "migrate",
+					Image:        "registry/demo/migrate:v1.0",
+					Command:      []string{"/bin/migrate", "--up"},
+					Env:          []corev1.EnvVar{{Name: "DATABASE_URL", Value: "postgres://db/web"}},
+					VolumeMounts: []corev1.VolumeMount{{Name: "schema", MountPath: "/schema"}},
+				}}
+				dc.Spec.Template.Spec.Volumes = []corev1.Volume{{Name: "schema", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				initContainers := deployment.Spec.Template.Spec.InitContainers
+				if len(initContainers) != 1 {
+					t.Fatalf("expected the migrate init container, got %#v", initContainers)
+				}
+				expected := newDeploymentConfig()
+				if !reflect.DeepEqual(initContainers[0].Command, []string{"/bin/migrate", "--up"}) ||
+					!reflect.DeepEqual(initContainers[0].VolumeMounts, []corev1.VolumeMount{{Name: "schema", MountPath: "/schema"}}) ||
+					initContainers[0].Image != "registry/demo/migrate:v1.0" || len(initContainers[0].Env) != 1 {
+					t.Errorf("expected the migrate init container with its command, env and volume mounts, got %#v", initContainers[0])
+				}
+				if !reflect.DeepEqual(deployment.Spec.Template.Spec.Containers, expected.Spec.Template.Spec.Containers) {
+					t.Errorf("expected the containers to be unchanged, got %#v", deployment.Spec.Template.Spec.Containers)
+				}
+			},
+		},
+		{
+			name: "test deployment config",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Test = true
+				return dc
+			},
+			expectedErr: `deployment config "demo/web" is a test deployment config`,
+		},
+		{
+			name: "allowed test deployment config",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Test = true
+				return dc
+			},
+			opts: Options{AllowTest: true},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 3 {
+					t.Errorf("expected a regular deployment with 3 replicas, got %v", deployment.Spec.Replicas)
+				}
+			},
+		},
+		{
+			name: "replicas override",
+			dc:   newDeploymentConfig,
+			opts: Options{Replicas: func() *int32 { replicas := int32(0); return &replicas }()},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 0 {
+					t.Errorf("expected 0 replicas, got %v", deployment.Spec.Replicas)
+				}
+			},
+		},
+		{
+			name: "replicas override of autoscaled deployment config",
+			dc:   newDeploymentConfig,
+			opts: Options{Autoscaled: true, Replicas: func() *int32 { replicas := int32(1); return &replicas }()},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 1 {
+					t.Errorf("expected 1 replica, got %v", deployment.Spec.Replicas)
+				}
+			},
+		},
+		{
+			name: "provenance annotations",
+			dc:   newDeploymentConfig,
+			opts: Options{Version: "v1.0", MigratedAt: time.Date(2018, 5, 1, 10, 0, 0, 0, time.FixedZone("CEST", 2*60*60))},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				expected := map[string]string{
+					SourceDeploymentConfigAnnotation: "web",
+					MigratedAtAnnotation:             "2018-05-01T08:00:00Z",
+					MigratedByVersionAnnotation:      "v1.0",
+				}
+				if provenance := ProvenanceAnnotations(deployment); !reflect.DeepEqual(provenance, expected) {
+					t.Errorf("expected provenance annotations %v, got %v", expected, provenance)
+				}
+			},
+		},
+		{
+			name: "recreate strategy timeout",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Strategy = osappsv1.DeploymentStrategy{
+					Type:           osappsv1.DeploymentStrategyTypeRecreate,
+					RecreateParams: &osappsv1.RecreateDeploymentStrategyParams{TimeoutSeconds: int64Ptr(120)},
+				}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if deployment.Spec.ProgressDeadlineSeconds == nil || *deployment.Spec.ProgressDeadlineSeconds != 120 {
+					t.Errorf("expected 120 seconds progress deadline, got %v", deployment.Spec.ProgressDeadlineSeconds)
+				}
+			},
+		},
+		{
+			name: "recreate strategy zero timeout",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Strategy = osappsv1.DeploymentStrategy{
+					Type:           osappsv1.DeploymentStrategyTypeRecreate,
+					RecreateParams: &osappsv1.RecreateDeploymentStrategyParams{TimeoutSeconds: int64Ptr(0)},
+				}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if deployment.Spec.ProgressDeadlineSeconds != nil {
+					t.Errorf("expected the default progress deadline, got %d", *deployment.Spec.ProgressDeadlineSeconds)
+				}
+			},
+		},
+		{
+			name: "timeout of the strategy type",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Strategy = osappsv1.DeploymentStrategy{
+					Type:           osappsv1.DeploymentStrategyTypeRolling,
+					RollingParams:  &osappsv1.RollingDeploymentStrategyParams{TimeoutSeconds: int64Ptr(300)},
+					RecreateParams: &osappsv1.RecreateDeploymentStrategyParams{TimeoutSeconds: int64Ptr(120)},
+				}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if deployment.Spec.ProgressDeadlineSeconds == nil || *deployment.Spec.ProgressDeadlineSeconds != 300 {
+					t.Errorf("expected the rolling 300 seconds progress deadline, got %v", deployment.Spec.ProgressDeadlineSeconds)
+				}
+			},
+		},
+		{
+			name: "image change triggers per container",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Spec.Containers = append(dc.Spec.Template.Spec.Containers, corev1.Container{Name: "proxy", Image: "proxy:1"})
+				dc.Spec.Triggers = osappsv1.DeploymentTriggerPolicies{
+					{
+						Type: osappsv1.DeploymentTriggerOnImageChange,
+						ImageChangeParams: &osappsv1.DeploymentTriggerImageChangeParams{
+							Automatic:          true,
+							ContainerNames:     []string{"web"},
+							From:               corev1.ObjectReference{Kind: "ImageStreamTag", Name: "web:latest"},
+							LastTriggeredImage: "registry/demo/web@sha256:1",
+						},
+					},
+					{
+						Type: osappsv1.DeploymentTriggerOnImageChange,
+						ImageChangeParams: &osappsv1.DeploymentTriggerImageChangeParams{
+							Automatic:          true,
+							ContainerNames:     []string{"proxy"},
+							From:               corev1.ObjectReference{Kind: "ImageStreamTag", Name: "proxy:stable"},
+							LastTriggeredImage: "registry/demo/proxy@sha256:2",
+						},
+					},
+				}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				containers := deployment.Spec.Template.Spec.Containers
+				if len(containers) != 2 {
+					t.Fatalf("expected 2 containers, got %d", len(containers))
+				}
+				if containers[0].Name != "web" || containers[0].Image != "registry/demo/web@sha256:1" {
+					t.Errorf("expected the web container with the web image, got %q with %q", containers[0].Name, containers[0].Image)
+				}
+				if containers[1].Name != "proxy" || containers[1].Image != "registry/demo/proxy@sha256:2" {
+					t.Errorf("expected the proxy container with the proxy image, got %q with %q", containers[1].Name, containers[1].Image)
+				}
+			},
+		},
+		{
+			name: "security contexts",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Spec.SecurityContext = &corev1.PodSecurityContext{FSGroup: int64Ptr(1000), SupplementalGroups: []int64{2000}}
+				runAsNonRoot := true
+				dc.Spec.Template.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{RunAsNonRoot: &runAsNonRoot, RunAsUser: int64Ptr(1001)}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				podContext := deployment.Spec.Template.Spec.SecurityContext
+				if podContext == nil || podContext.FSGroup == nil || *podContext.FSGroup != 1000 || !reflect.DeepEqual(podContext.SupplementalGroups, []int64{2000}) {
+					t.Errorf("expected the pod security context with fsGroup 1000, got %#v", podContext)
+				}
+				containerContext := deployment.Spec.Template.Spec.Containers[0].SecurityContext
+				if containerContext == nil || containerContext.RunAsNonRoot == nil || !*containerContext.RunAsNonRoot || *containerContext.RunAsUser != 1001 {
+					t.Errorf("expected the container security context with runAsNonRoot, got %#v", containerContext)
+				}
+			},
+		},
+		{
+			name: "pod lifecycle fields",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				podSpec := &dc.Spec.Template.Spec
+				podSpec.TerminationGracePeriodSeconds = int64Ptr(90)
+				podSpec.RestartPolicy = corev1.RestartPolicyAlways
+				podSpec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+				podSpec.DNSConfig = &corev1.PodDNSConfig{Nameservers: []string{"10.0.0.10"}}
+				podSpec.HostNetwork = true
+				podSpec.HostAliases = []corev1.HostAlias{{IP: "10.0.0.1", Hostnames: []string{"db.internal"}}}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				podSpec := deployment.Spec.Template.Spec
+				if podSpec.TerminationGracePeriodSeconds == nil || *podSpec.TerminationGracePeriodSeconds != 90 {
+					t.Errorf("expected termination grace period 90, got %v", podSpec.TerminationGracePeriodSeconds)
+				}
+				if !reflect.DeepEqual(podSpec.HostAliases, []corev1.HostAlias{{IP: "10.0.0.1", Hostnames: []string{"db.internal"}}}) {
+					t.Errorf("expected the host aliases, got %v", podSpec.HostAliases)
+				}
+				if podSpec.RestartPolicy != corev1.RestartPolicyAlways || podSpec.DNSPolicy != corev1.DNSClusterFirstWithHostNet || !podSpec.HostNetwork {
+					t.Errorf("expected the restart policy, dns policy and host network, got %q %q %t", podSpec.RestartPolicy, podSpec.DNSPolicy, podSpec.HostNetwork)
+				}
+				if podSpec.DNSConfig == nil || !reflect.DeepEqual(podSpec.DNSConfig.Nameservers, []string{"10.0.0.10"}) {
+					t.Errorf("expected the dns config, got %#v", podSpec.DNSConfig)
+				}
+			},
+		},
+		{
+			name: "pod template annotations",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Annotations = map[string]string{"sidecar.istio.io/inject": "true", "checksum/config": "abc123"}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				expected := map[string]string{"sidecar.istio.io/inject": "true", "checksum/config": "abc123"}
+				if !reflect.DeepEqual(deployment.Spec.Template.Annotations, expected) {
+					t.Errorf("expected pod template annotations %v, got %v", expected, deployment.Spec.Template.Annotations)
+				}
+				if _, ok := deployment.Annotations["sidecar.istio.io/inject"]; ok {
+					t.Errorf("expected the pod template annotations not on the deployment, got %v", deployment.Annotations)
+				}
+			},
+		},
+		{
+			name: "named container ports and probes",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				container := &dc.Spec.Template.Spec.Containers[0]
+				container.Ports = []corev1.ContainerPort{{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP}}
+				container.ReadinessProbe = &corev1.Probe{
+					Handler: corev1.Handler{HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromString("http")}},
+				}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				container := deployment.Spec.Template.Spec.Containers[0]
+				if !reflect.DeepEqual(container.Ports, []corev1.ContainerPort{{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP}}) {
+					t.Errorf("expected the named http port, got %v", container.Ports)
+				}
+				if probe := container.ReadinessProbe; probe == nil || probe.HTTPGet == nil || probe.HTTPGet.Port != intstr.FromString("http") {
+					t.Errorf("expected the readiness probe referencing the http port, got %#v", probe)
+				}
+			},
+		},
+		{
+			name: "without pod template",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template = nil
+				return dc
+			},
+			expectedErr: `deployment config "demo/web" has no pod template`,
+		},
+		{
+			name: "injected service account token volumes",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Spec.Volumes = []corev1.Volume{
+					{Name: "kube-api-access-x2b9z", VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{}}},
+					{Name: "api-access", VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{}}},
+				}
+				dc.Spec.Template.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{
+					{Name: "kube-api-access-x2b9z", MountPath: "/var/run/secrets/kubernetes.io/serviceaccount"},
+					{Name: "api-access", MountPath: "/var/run/secrets/api"},
+				}
+				return dc
+			},
+			opts: Options{StripVolumePrefixes: DefaultStripVolumePrefixes},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				spec := deployment.Spec.Template.Spec
+				if len(spec.Volumes) != 1 || spec.Volumes[0].Name != "api-access" {
+					t.Errorf("expected only the user volume, got %#v", spec.Volumes)
+				}
+				expected := []corev1.VolumeMount{{Name: "api-access", MountPath: "/var/run/secrets/api"}}
+				if !reflect.DeepEqual(spec.Containers[0].VolumeMounts, expected) {
+					t.Errorf("expected only the user volume mount, got %#v", spec.Containers[0].VolumeMounts)
+				}
+			},
+		},
+		{
+			name: "injected volumes are kept without prefixes",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Spec.Volumes = []corev1.Volume{{Name: "kube-api-access-x2b9z"}}
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				if volumes := deployment.Spec.Template.Spec.Volumes; len(volumes) != 1 {
+					t.Errorf("expected the volume kept, got %#v", volumes)
+				}
+			},
+		},
+		{
+			name: "commands and arguments",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Spec.Containers[0].Command = []string{"/bin/sh", "-c", `exec nginx -g 'daemon off;' "$@"`, "--"}
+				dc.Spec.Template.Spec.Containers[0].Args = []string{"", " spaced ", "$(HOME)", "ünïcode\n"}
+				dc.Spec.Template.Spec.Containers = append(dc.Spec.Template.Spec.Containers, corev1.Container{
+					Name:  "sidecar",
+					Image: "sidecar:1",
+					Args:  []string{},
+				})
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				containers := deployment.Spec.Template.Spec.Containers
+				expectedCommand := []string{"/bin/sh", "-c", `exec nginx -g 'daemon off;' "$@"`, "--"}
+				if !reflect.DeepEqual(containers[0].Command, expectedCommand) {
+					t.Errorf("expected command %q, got %q", expectedCommand, containers[0].Command)
+				}
+				expectedArgs := []string{"", " spaced ", "$(HOME)", "ünïcode\n"}
+				if !reflect.DeepEqual(containers[0].Args, expectedArgs) {
+					t.Errorf("expected args %q, got %q", expectedArgs, containers[0].Args)
+				}
+				if containers[1].Command != nil {
+					t.Errorf("expected nil command kept, got %#v", containers[1].Command)
+				}
+				if containers[1].Args == nil || len(containers[1].Args) != 0 {
+					t.Errorf("expected empty args kept, got %#v", containers[1].Args)
+				}
+			},
+		},
+		{
+			name: "scheduler name and priority class",
+			dc: func() *osappsv1.DeploymentConfig {
+				dc := newDeploymentConfig()
+				dc.Spec.Template.Spec.SchedulerName = "batch-scheduler"
+				dc.Spec.Template.Spec.PriorityClassName = "high-priority"
+				return dc
+			},
+			validate: func(t *testing.T, deployment *appsv1.Deployment) {
+				podSpec := deployment.Spec.Template.Spec
+				if podSpec.SchedulerName != "batch-scheduler" {
+					t.Errorf("expected the batch-scheduler scheduler, got %q", podSpec.SchedulerName)
+				}
+				if podSpec.PriorityClassName != "high-priority" {
+					t.Errorf("expected the high-priority priority class, got %q", podSpec.PriorityClassName)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dc := test.dc()
+			original := dc.DeepCopy()
+			deployment, err := Convert(dc, test.opts)
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			test.validate(t, deployment)
+			if !reflect.DeepEqual(dc, original) {
+				t.Errorf("the deployment config was mutated")
+			}
+		})
+	}
+}
+
+func TestConvertRollingStrategy(t *testing.T) {
+	tests := []struct {
+		name                   string
+		params                 *osappsv1.RollingDeploymentStrategyParams
+		opts                   Options
+		expectedMaxSurge       intstr.IntOrString
+		expectedMaxUnavailable intstr.IntOrString
+		expectedDeadline       *int32
+	}{
+		{
+			name:                   "nil params",
+			expectedMaxSurge:       intstr.FromString("25%"),
+			expectedMaxUnavailable: intstr.FromString("25%"),
+		},
+		{
+			name: "percentage",
+			params: &osappsv1.RollingDeploymentStrategyParams{
+				MaxSurge:       intOrStringPtr(intstr.FromString("50%")),
+				MaxUnavailable: intOrStringPtr(intstr.FromString("10%")),
+			},
+			expectedMaxSurge:       intstr.FromString("50%"),
+			expectedMaxUnavailable: intstr.FromString("10%"),
+		},
+		{
+			name: "absolute numbers and timeout",
+			params: &osappsv1.RollingDeploymentStrategyParams{
+				MaxSurge:       intOrStringPtr(intstr.FromInt(2)),
+				MaxUnavailable: intOrStringPtr(intstr.FromInt(0)),
+				TimeoutSeconds: int64Ptr(300),
+			},
+			expectedMaxSurge:       intstr.FromInt(2),
+			expectedMaxUnavailable: intstr.FromInt(0),
+			expectedDeadline:       int32Ptr(300),
+		},
+		{
+			name: "only max surge",
+			params: &osappsv1.RollingDeploymentStrategyParams{
+				MaxSurge: intOrStringPtr(intstr.FromInt(1)),
+			},
+			expectedMaxSurge:       intstr.FromInt(1),
+			expectedMaxUnavailable: intstr.FromString("25%"),
+		},
+		{
+			name: "overridden max surge and max unavailable",
+			params: &osappsv1.RollingDeploymentStrategyParams{
+				MaxSurge:       intOrStringPtr(intstr.FromString("50%")),
+				MaxUnavailable: intOrStringPtr(intstr.FromString("10%")),
+			},
+			opts: Options{
+				MaxSurge:       intOrStringPtr(intstr.FromInt(1)),
+				MaxUnavailable: intOrStringPtr(intstr.FromString("0%")),
+			},
+			expectedMaxSurge:       intstr.FromInt(1),
+			expectedMaxUnavailable: intstr.FromString("0%"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dc := newDeploymentConfig()
+			dc.Spec.Strategy.RollingParams = test.params
+			deployment, err := Convert(dc, test.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			strategy := deployment.Spec.Strategy
+			if strategy.Type != appsv1.RollingUpdateDeploymentStrategyType || strategy.RollingUpdate == nil {
+				t.Fatalf("expected rolling update strategy, got %#v", strategy)
+			}
+			if *strategy.RollingUpdate.MaxSurge != test.expectedMaxSurge {
+				t.Errorf("expected max surge %s, got %s", test.expectedMaxSurge.String(), strategy.RollingUpdate.MaxSurge.String())
+			}
+			if *strategy.RollingUpdate.MaxUnavailable != test.expectedMaxUnavailable {
+				t.Errorf("expected max unavailable %s, got %s", test.expectedMaxUnavailable.String(), strategy.RollingUpdate.MaxUnavailable.String())
+			}
+			if !reflect.DeepEqual(deployment.Spec.ProgressDeadlineSeconds, test.expectedDeadline) {
+				t.Errorf("expected progress deadline %v, got %v", test.expectedDeadline, deployment.Spec.ProgressDeadlineSeconds)
+			}
+		})
+	}
+}
+
+func TestConvertDeprecatedAffinity(t *testing.T) {
+	dc := newDeploymentConfig()
+	if HasDeprecatedAffinity(dc) {
+		t.Fatalf("expected no deprecated affinity")
+	}
+	dc.Spec.Template.Annotations = map[string]string{DeprecatedAffinityAnnotation: `{"nodeAffinity":{}}`}
+	if !HasDeprecatedAffinity(dc) {
+		t.Fatalf("expected deprecated affinity")
+	}
+	report := NewReport(dc.Namespace, dc.Name)
+	if _, err := Convert(dc, Options{Report: report}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{`"scheduler.alpha.kubernetes.io/affinity" annotation (not honored by the scheduler)`}
+	if !reflect.DeepEqual(report.Dropped, expected) {
+		t.Errorf("expected dropped %v, got %v", expected, report.Dropped)
+	}
+}
+
+func TestConvertCustomStrategyReport(t *testing.T) {
+	dc := newDeploymentConfig()
+	dc.Spec.Strategy = osappsv1.DeploymentStrategy{
+		Type: osappsv1.DeploymentStrategyTypeCustom,
+		CustomParams: &osappsv1.CustomDeploymentStrategyParams{
+			Image:       "deployer:1",
+			Command:     []string{"/deploy", "--canary"},
+			Environment: []corev1.EnvVar{{Name: "CANARY", Value: "true"}, {Name: "STEPS", Value: "3"}},
+		},
+	}
+	report := NewReport(dc.Namespace, dc.Name)
+	if _, err := Convert(dc, Options{Force: true, Report: report}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{`custom deployer image "deployer:1" with command "/deploy --canary" and environment [CANARY, STEPS] (recorded in "migrate-to-deployment/custom-strategy" annotation)`}
+	if !reflect.DeepEqual(report.Dropped, expected) {
+		t.Errorf("expected dropped %v, got %v", expected, report.Dropped)
+	}
+}
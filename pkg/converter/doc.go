@@ -0,0 +1,8 @@
+// Package converter converts OpenShift deployment configs into Kubernetes deployments.
+//
+// The conversion does not talk to the cluster (except for the optional image resolver), so it can be
+// embedded into other tools and controllers:
+//
+//	deployment, err := converter.Convert(dc, converter.Options{})
+//	replicaSets, err := converter.MigrateHistory(deployment, replicationControllers)
+package converter
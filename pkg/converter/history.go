@@ -0,0 +1,293 @@
+package converter
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+const (
+	// DeploymentConfigVersionAnnotation is set on replication controllers created by deployment configs
+	// and holds the deployment config version the replication controller was created for.
+	// TODO: Move this to openshift/api
+	DeploymentConfigVersionAnnotation = "openshift.io/deployment-config.latest-version"
+
+	// DeploymentPhaseAnnotation is set on replication controllers created by deployment configs and holds
+	// the phase of the rollout (New, Pending, Running, Complete or Failed).
+	// TODO: Move this to openshift/api
+	DeploymentPhaseAnnotation = "openshift.io/deployment.phase"
+
+	// DeploymentRevisionAnnotation is the revision annotation the deployment controller uses for replica sets.
+	DeploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+	// DeploymentRevisionHistoryAnnotation is the annotation the deployment controller uses for the older
+	// revisions of a replica set whose pod template was rolled out again.
+	DeploymentRevisionHistoryAnnotation = "deployment.kubernetes.io/revision-history"
+
+	// OriginalReplicasAnnotation is set on the replication controllers scaled down by the migration and holds
+	// their replicas before the migration, so they can be scaled back up when the migration is undone.
+	OriginalReplicasAnnotation = "migrate-to-deployment/original-replicas"
+
+	// PodTemplateHashLabel is the label the deployment controller uses to tell replica sets apart.
+	PodTemplateHashLabel = "pod-template-hash"
+)
+
+// injectedTemplateLabels are the labels the deployment config controller injects into the pod template of
+// the replication controllers.
+var injectedTemplateLabels = []string{
+	DeploymentConfigLabel,
+	"deployment",
+}
+
+// injectedTemplateAnnotations are the annotations the deployment config controller injects into the pod
+// template of the replication controllers.
+var injectedTemplateAnnotations = []string{
+	"openshift.io/deployment.name",
+	"openshift.io/deployment-config.name",
+	DeploymentConfigVersionAnnotation,
+}
+
+// MigrateHistory converts the replication controllers managed by a deployment config into replica sets
+// owned by the given deployment, so the deployment keeps the rollout history of the deployment config.
+// The replica sets are returned in the order of the replication controllers. The replication controllers
+// with the same pod template are merged into a single replica set with the latest revision and the older
+// revisions in the revision history annotation, like the deployment controller does for a rolled back template.
+// The replication controllers without the version annotation get synthetic revisions, see historyRevisions().
+func MigrateHistory(deployment *appsv1.Deployment, rcs []corev1.ReplicationController) ([]*appsv1.ReplicaSet, error) {
+	revisions, err := historyRevisions(rcs)
+	if err != nil {
+		return nil, err
+	}
+	result := []*appsv1.ReplicaSet{}
+	byName := map[string]int{}
+	for i := range rcs {
+		rs, err := convertReplicationController(&rcs[i], deployment, revisions[i])
+		if err != nil {
+			return nil, err
+		}
+		if j, ok := byName[rs.Name]; ok {
+			mergeRevisions(result[j], rs)
+			continue
+		}
+		byName[rs.Name] = len(result)
+		result = append(result, rs)
+	}
+	return result, nil
+}
+
+// mergeRevisions merges the revision of the duplicate replica set into the replica set with the same pod
+// template. The replica set keeps the latest revision and the other revisions go to the revision history.
+func mergeRevisions(rs, duplicate *appsv1.ReplicaSet) {
+	revisions := []int64{}
+	for _, annotations := range []map[string]string{rs.Annotations, duplicate.Annotations} {
+		values := []string{annotations[DeploymentRevisionAnnotation]}
+		if history := annotations[DeploymentRevisionHistoryAnnotation]; len(history) > 0 {
+			values = append(values, strings.Split(history, ",")...)
+		}
+		for _, value := range values {
+			// The revisions were formatted by convertReplicationController
+			revision, _ := strconv.ParseInt(value, 10, 64)
+			revisions = append(revisions, revision)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i] < revisions[j] })
+
+	history := []string{}
+	for _, revision := range revisions[:len(revisions)-1] {
+		history = append(history, strconv.FormatInt(revision, 10))
+	}
+	rs.Annotations[DeploymentRevisionAnnotation] = strconv.FormatInt(revisions[len(revisions)-1], 10)
+	rs.Annotations[DeploymentRevisionHistoryAnnotation] = strings.Join(history, ",")
+}
+
+// ConvertReplicationController converts the replication controller managed by a deployment config into
+// a replica set owned by the given deployment. The revision of the replication controller is preserved,
+// so the rollout history of the deployment matches the deployment config history.
+// The replica set is scaled down, the deployment controller will scale it up if it matches the current
+// deployment pod template.
+func ConvertReplicationController(rc *corev1.ReplicationController, deployment *appsv1.Deployment) (*appsv1.ReplicaSet, error) {
+	if _, ok := rc.Annotations[DeploymentConfigVersionAnnotation]; !ok {
+		return nil, fmt.Errorf("replication controller %q has no %q annotation", rc.Namespace+"/"+rc.Name, DeploymentConfigVersionAnnotation)
+	}
+	revision, err := parseRevision(rc)
+	if err != nil {
+		return nil, err
+	}
+	return convertReplicationController(rc, deployment, revision)
+}
+
+// convertReplicationController converts the replication controller into a replica set with the given revision.
+func convertReplicationController(rc *corev1.ReplicationController, deployment *appsv1.Deployment, revision int64) (*appsv1.ReplicaSet, error) {
+	if rc.Spec.Template == nil {
+		return nil, fmt.Errorf("replication controller %q has no pod template", rc.Namespace+"/"+rc.Name)
+	}
+
+	template := rc.Spec.Template.DeepCopy()
+	stripInjectedKeys(template, &deployment.Spec.Template)
+	hash := ComputeHash(template)
+	if template.Labels == nil {
+		template.Labels = map[string]string{}
+	}
+	template.Labels[PodTemplateHashLabel] = hash
+
+	selector := map[string]string{}
+	for k, v := range template.Labels {
+		selector[k] = v
+	}
+
+	annotations := ProvenanceAnnotations(deployment)
+	annotations[DeploymentRevisionAnnotation] = strconv.FormatInt(revision, 10)
+
+	replicas := int32(0)
+	isController := true
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        deployment.Name + "-" + hash,
+			Namespace:   deployment.Namespace,
+			Labels:      selector,
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion:         appsv1.SchemeGroupVersion.String(),
+					Kind:               "Deployment",
+					Name:               deployment.Name,
+					UID:                deployment.UID,
+					Controller:         &isController,
+					BlockOwnerDeletion: &isController,
+				},
+			},
+		},
+		Spec: appsv1.ReplicaSetSpec{
+			Replicas:        &replicas,
+			MinReadySeconds: rc.Spec.MinReadySeconds,
+			Selector:        &metav1.LabelSelector{MatchLabels: selector},
+			Template:        *template,
+		},
+	}, nil
+}
+
+// stripInjectedKeys removes the labels and annotations injected by the deployment config controller from the
+// replication controller pod template, so the replica set of the current rollout has the same pod template and
+// hash as the deployment and the deployment controller adopts it instead of rolling out. The injected keys the
+// deployment pod template has with the same value are kept, e.g. the deploymentconfig label services select on.
+func stripInjectedKeys(template, deploymentTemplate *corev1.PodTemplateSpec) {
+	for _, key := range injectedTemplateLabels {
+		if value, ok := deploymentTemplate.Labels[key]; !ok || value != template.Labels[key] {
+			delete(template.Labels, key)
+		}
+	}
+	for _, key := range injectedTemplateAnnotations {
+		if value, ok := deploymentTemplate.Annotations[key]; !ok || value != template.Annotations[key] {
+			delete(template.Annotations, key)
+		}
+	}
+	// The hash tells an empty map from a missing one
+	if len(template.Annotations) == 0 {
+		template.Annotations = nil
+		if deploymentTemplate.Annotations != nil {
+			template.Annotations = map[string]string{}
+		}
+	}
+}
+
+// LatestCompleteReplicationController returns the replication controller of the latest complete rollout,
+// which runs the application, or nil when no rollout completed.
+func LatestCompleteReplicationController(rcs []corev1.ReplicationController) *corev1.ReplicationController {
+	var latest *corev1.ReplicationController
+	latestRevision := int64(0)
+	for i := range rcs {
+		if rcs[i].Annotations[DeploymentPhaseAnnotation] != "Complete" {
+			continue
+		}
+		revision, err := parseRevision(&rcs[i])
+		if err != nil {
+			continue
+		}
+		if revision > latestRevision {
+			latest, latestRevision = &rcs[i], revision
+		}
+	}
+	return latest
+}
+
+// historyRevisions returns the revisions of the replication controllers, in the order of the replication
+// controllers. The revision is read from the version annotation. Replication controllers created by old
+// deployment configs may lack the annotation, those get a synthetic revision following the revision of the
+// replication controller created before them, skipping the revisions already in use, so the revisions keep
+// the creation order.
+func historyRevisions(rcs []corev1.ReplicationController) ([]int64, error) {
+	revisions := make([]int64, len(rcs))
+	used := map[int64]bool{}
+	order := make([]int, len(rcs))
+	for i := range rcs {
+		order[i] = i
+		if _, ok := rcs[i].Annotations[DeploymentConfigVersionAnnotation]; !ok {
+			continue
+		}
+		revision, err := parseRevision(&rcs[i])
+		if err != nil {
+			return nil, err
+		}
+		revisions[i] = revision
+		used[revision] = true
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		first, second := &rcs[order[a]], &rcs[order[b]]
+		if !first.CreationTimestamp.Equal(&second.CreationTimestamp) {
+			return first.CreationTimestamp.Before(&second.CreationTimestamp)
+		}
+		return first.Name < second.Name
+	})
+
+	last := int64(0)
+	for _, i := range order {
+		if revisions[i] == 0 {
+			revision := last + 1
+			for used[revision] {
+				revision++
+			}
+			revisions[i] = revision
+			used[revision] = true
+		}
+		if revisions[i] > last {
+			last = revisions[i]
+		}
+	}
+	return revisions, nil
+}
+
+// parseRevision returns the revision in the version annotation of the replication controller.
+func parseRevision(rc *corev1.ReplicationController) (int64, error) {
+	value := rc.Annotations[DeploymentConfigVersionAnnotation]
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || revision < 1 {
+		return 0, fmt.Errorf("replication controller %q has invalid revision %q", rc.Namespace+"/"+rc.Name, value)
+	}
+	return revision, nil
+}
+
+// ComputeHash returns the pod-template-hash of the given pod template, computed the same way the deployment
+// controller computes it for new replica sets, so the replica sets created for the history get the names
+// the deployment controller would give them. The hash is computed over the Go representation of the pod
+// template, so it only matches controllers built with the same API types.
+func ComputeHash(template *corev1.PodTemplateSpec) string {
+	hasher := fnv.New32a()
+	printer := spew.ConfigState{
+		Indent:         " ",
+		SortKeys:       true,
+		DisableMethods: true,
+		SpewKeys:       true,
+	}
+	printer.Fprintf(hasher, "%#v", *template)
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+}
@@ -0,0 +1,319 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// newReplicationController returns a replication controller of the demo/web deployment config created at
+// the given minute, with the given version annotation (none when empty).
+func newReplicationController(name, version string, minute int) corev1.ReplicationController {
+	rc := corev1.ReplicationController{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "demo",
+			Annotations:       map[string]string{},
+			CreationTimestamp: metav1.NewTime(time.Date(2018, 5, 1, 10, minute, 0, 0, time.UTC)),
+		},
+		Spec: corev1.ReplicationControllerSpec{
+			Template: newDeploymentConfig().Spec.Template.DeepCopy(),
+		},
+	}
+	if len(version) > 0 {
+		rc.Annotations[DeploymentConfigVersionAnnotation] = version
+	}
+	return rc
+}
+
+func TestConvertReplicationController(t *testing.T) {
+	deployment, err := Convert(newDeploymentConfig(), Options{Version: "v1.0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deployment.UID = types.UID("uid")
+
+	names := map[string]bool{}
+	for i, version := range []string{"1", "2", "3"} {
+		rc := newReplicationController("web-"+version, version, i)
+		rc.Spec.Template.Spec.Containers[0].Image = "nginx:" + version
+		rc.Spec.MinReadySeconds = 5
+
+		rs, err := ConvertReplicationController(&rc, deployment)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		hash := rs.Labels[PodTemplateHashLabel]
+		if len(hash) == 0 || rs.Name != "web-"+hash {
+			t.Errorf("expected replica set named by the pod template hash, got %q with hash %q", rs.Name, hash)
+		}
+		if names[rs.Name] {
+			t.Errorf("expected different replica sets for different templates, got %q twice", rs.Name)
+		}
+		names[rs.Name] = true
+		if rs.Spec.Template.Labels[PodTemplateHashLabel] != hash || rs.Spec.Selector.MatchLabels[PodTemplateHashLabel] != hash {
+			t.Errorf("expected the pod template hash in the template labels and selector of %q", rs.Name)
+		}
+		if revision := rs.Annotations[DeploymentRevisionAnnotation]; revision != version {
+			t.Errorf("expected revision %q, got %q", version, revision)
+		}
+		if source := rs.Annotations[SourceDeploymentConfigAnnotation]; source != "web" {
+			t.Errorf("expected replica set %q converted from deployment config web, got %q", rs.Name, source)
+		}
+		if rs.Annotations[MigratedByVersionAnnotation] != "v1.0" || rs.Annotations[MigratedAtAnnotation] != deployment.Annotations[MigratedAtAnnotation] {
+			t.Errorf("expected the deployment provenance annotations on %q, got %v", rs.Name, rs.Annotations)
+		}
+		if rs.Spec.Replicas == nil || *rs.Spec.Replicas != 0 {
+			t.Errorf("expected replica set %q scaled down, got %v", rs.Name, rs.Spec.Replicas)
+		}
+		if rs.Spec.MinReadySeconds != 5 {
+			t.Errorf("expected 5 min ready seconds, got %d", rs.Spec.MinReadySeconds)
+		}
+		owner := rs.OwnerReferences
+		if len(owner) != 1 || owner[0].Kind != "Deployment" || owner[0].Name != "web" || owner[0].UID != "uid" || !*owner[0].Controller {
+			t.Errorf("expected replica set %q controlled by the deployment, got %#v", rs.Name, owner)
+		}
+	}
+
+	for expectedErr, rc := range map[string]corev1.ReplicationController{
+		`replication controller "demo/web-1" has no pod template`: func() corev1.ReplicationController {
+			rc := newReplicationController("web-1", "1", 1)
+			rc.Spec.Template = nil
+			return rc
+		}(),
+		`replication controller "demo/web-1" has no "openshift.io/deployment-config.latest-version" annotation`: newReplicationController("web-1", "", 1),
+		`replication controller "demo/web-1" has invalid revision "first"`:                                      newReplicationController("web-1", "first", 1),
+	} {
+		if _, err := ConvertReplicationController(&rc, deployment); err == nil || !strings.Contains(err.Error(), expectedErr) {
+			t.Errorf("expected error containing %q, got %v", expectedErr, err)
+		}
+	}
+}
+
+func TestHistoryRevisions(t *testing.T) {
+	tests := []struct {
+		name        string
+		rcs         []corev1.ReplicationController
+		expected    []int64
+		expectedErr string
+	}{
+		{
+			name: "version annotations",
+			rcs: []corev1.ReplicationController{
+				newReplicationController("web-2", "2", 2),
+				newReplicationController("web-1", "1", 1),
+			},
+			expected: []int64{2, 1},
+		},
+		{
+			name: "synthetic revisions follow the creation order",
+			rcs: []corev1.ReplicationController{
+				newReplicationController("web-c", "", 3),
+				newReplicationController("web-a", "", 1),
+				newReplicationController("web-b", "", 2),
+			},
+			expected: []int64{3, 1, 2},
+		},
+		{
+			name: "synthetic revisions skip the revisions in use",
+			rcs: []corev1.ReplicationController{
+				newReplicationController("web-a", "", 1),
+				newReplicationController("web-2", "2", 2),
+				newReplicationController("web-b", "", 3),
+				newReplicationController("web-1", "1", 4),
+			},
+			expected: []int64{3, 2, 4, 1},
+		},
+		{
+			name: "same creation time ordered by name",
+			rcs: []corev1.ReplicationController{
+				newReplicationController("web-b", "", 1),
+				newReplicationController("web-a", "", 1),
+			},
+			expected: []int64{2, 1},
+		},
+		{
+			name:        "invalid version annotation",
+			rcs:         []corev1.ReplicationController{newReplicationController("web-1", "first", 1)},
+			expectedErr: `replication controller "demo/web-1" has invalid revision "first"`,
+		},
+		{
+			name:     "no replication controllers",
+			expected: []int64{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			revisions, err := historyRevisions(test.rcs)
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(revisions, test.expected) {
+				t.Errorf("expected revisions %v, got %v", test.expected, revisions)
+			}
+		})
+	}
+}
+
+func TestMigrateHistory(t *testing.T) {
+	deployment, err := Convert(newDeploymentConfig(), Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	changed := newReplicationController("web-1", "1", 1)
+	changed.Spec.Template.Spec.Containers[0].Image = "nginx:0"
+	rcs := []corev1.ReplicationController{changed, newReplicationController("web-2", "2", 2)}
+	original := []corev1.ReplicationController{*rcs[0].DeepCopy(), *rcs[1].DeepCopy()}
+
+	replicaSets, err := MigrateHistory(deployment, rcs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	revisions := []string{}
+	for _, rs := range replicaSets {
+		revisions = append(revisions, rs.Annotations[DeploymentRevisionAnnotation])
+	}
+	if !reflect.DeepEqual(revisions, []string{"1", "2"}) {
+		t.Errorf("expected the replica sets in the order of the replication controllers, got revisions %v", revisions)
+	}
+	if !reflect.DeepEqual(rcs, original) {
+		t.Errorf("the replication controllers were mutated")
+	}
+
+	synthetic, err := MigrateHistory(deployment, []corev1.ReplicationController{newReplicationController("web-a", "", 1)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if revision := synthetic[0].Annotations[DeploymentRevisionAnnotation]; revision != "1" {
+		t.Errorf("expected the synthetic revision 1, got %q", revision)
+	}
+
+	// The replication controllers of a template rolled out again are merged into one replica set
+	merged, err := MigrateHistory(deployment, []corev1.ReplicationController{
+		newReplicationController("web-1", "1", 1),
+		newReplicationController("web-2", "2", 2),
+		newReplicationController("web-3", "3", 3),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(merged) != 1 || merged[0].Annotations[DeploymentRevisionAnnotation] != "3" || merged[0].Annotations[DeploymentRevisionHistoryAnnotation] != "1,2" {
+		t.Errorf("expected a single replica set with revision 3 and revision history 1,2, got %d replica sets", len(merged))
+	}
+
+	invalid := newReplicationController("web-3", "third", 3)
+	if _, err := MigrateHistory(deployment, append(rcs, invalid)); err == nil || !strings.Contains(err.Error(), `has invalid revision "third"`) {
+		t.Errorf("expected the invalid revision error, got %v", err)
+	}
+}
+
+func TestMigrateHistoryAdoptedByDeployment(t *testing.T) {
+	dc := newDeploymentConfig()
+	deployment, err := Convert(dc, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The replication controller of the latest rollout has the deployment config template with the labels
+	// and annotations injected by the deployment config controller
+	rc := newReplicationController("web-2", "2", 2)
+	rc.Spec.Template = dc.Spec.Template.DeepCopy()
+	rc.Spec.Template.Labels[DeploymentConfigLabel] = "web"
+	rc.Spec.Template.Labels["deployment"] = "web-2"
+	rc.Spec.Template.Annotations = map[string]string{
+		"openshift.io/deployment.name":        "web-2",
+		"openshift.io/deployment-config.name": "web",
+		DeploymentConfigVersionAnnotation:     "2",
+	}
+
+	replicaSets, err := MigrateHistory(deployment, []corev1.ReplicationController{rc})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rs := replicaSets[0]
+	if expected := deployment.Name + "-" + ComputeHash(&deployment.Spec.Template); rs.Name != expected {
+		t.Errorf("expected replica set %q named by the deployment pod template hash, got %q", expected, rs.Name)
+	}
+	template := rs.Spec.Template.DeepCopy()
+	delete(template.Labels, PodTemplateHashLabel)
+	if !reflect.DeepEqual(*template, deployment.Spec.Template) {
+		t.Errorf("expected the replica set pod template to match the deployment pod template\nexpected: %#v\ngot:      %#v", deployment.Spec.Template, *template)
+	}
+}
+
+func TestComputeHash(t *testing.T) {
+	template := &corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "web", Image: "nginx:1"}},
+		},
+	}
+	// The hash the deployment controller computes for the template
+	if hash := ComputeHash(template); hash != "54979d7754" {
+		t.Errorf("expected hash %q, got %q", "54979d7754", hash)
+	}
+}
+
+func TestLatestCompleteReplicationController(t *testing.T) {
+	complete := func(rc corev1.ReplicationController) corev1.ReplicationController {
+		rc.Annotations[DeploymentPhaseAnnotation] = "Complete"
+		return rc
+	}
+	failed := newReplicationController("web-3", "3", 3)
+	failed.Annotations[DeploymentPhaseAnnotation] = "Failed"
+
+	tests := []struct {
+		name         string
+		rcs          []corev1.ReplicationController
+		expectedName string
+	}{
+		{
+			name: "none complete",
+			rcs:  []corev1.ReplicationController{newReplicationController("web-1", "1", 1), failed},
+		},
+		{
+			name: "latest complete",
+			rcs: []corev1.ReplicationController{
+				complete(newReplicationController("web-2", "2", 2)),
+				complete(newReplicationController("web-1", "1", 1)),
+				failed,
+			},
+			expectedName: "web-2",
+		},
+		{
+			name: "invalid version",
+			rcs: []corev1.ReplicationController{
+				complete(newReplicationController("web-1", "1", 1)),
+				complete(newReplicationController("web-x", "x", 2)),
+			},
+			expectedName: "web-1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rc := LatestCompleteReplicationController(test.rcs)
+			if len(test.expectedName) == 0 {
+				if rc != nil {
+					t.Errorf("expected no replication controller, got %q", rc.Name)
+				}
+				return
+			}
+			if rc == nil || rc.Name != test.expectedName {
+				t.Errorf("expected replication controller %q, got %v", test.expectedName, rc)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package converter
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	osappsv1 "github.com/openshift/api/apps/v1"
+)
+
+const (
+	// HookStageLabel is set on the jobs converted from lifecycle hooks and holds the hook stage.
+	HookStageLabel = "migrate-to-deployment/hook"
+	// HookFailurePolicyAnnotation is set on the jobs converted from lifecycle hooks and holds the hook
+	// failure policy, which decides whether a failed hook job fails the migration.
+	HookFailurePolicyAnnotation = "migrate-to-deployment/hook-failure-policy"
+)
+
+// HasLifecycleHooks returns true when the deployment config strategy defines any
+// lifecycle hooks. Deployments do not support lifecycle hooks natively. The hooks of the
+// strategy parameters not matching the strategy type are ignored, like the deployer does.
+func HasLifecycleHooks(dc *osappsv1.DeploymentConfig) bool {
+	pre, post := lifecycleHooks(dc)
+	return pre != nil || post != nil || HasMidLifecycleHook(dc)
+}
+
+// HasPostLifecycleHook returns true when the deployment config strategy defines the post hook.
+func HasPostLifecycleHook(dc *osappsv1.DeploymentConfig) bool {
+	_, post := lifecycleHooks(dc)
+	return post != nil
+}
+
+// HasMidLifecycleHook returns true when the deployment config recreate strategy defines the mid hook.
+// The mid hook runs while the application is scaled down and has no equivalent outside deployment configs.
+func HasMidLifecycleHook(dc *osappsv1.DeploymentConfig) bool {
+	strategy := dc.Spec.Strategy
+	return strategy.Type == osappsv1.DeploymentStrategyTypeRecreate && strategy.RecreateParams != nil && strategy.RecreateParams.Mid != nil
+}
+
+// LifecycleHookJobs converts the pre and post lifecycle hooks of the deployment config into jobs.
+// The hook containers are based on the given deployment pod template, which has the images resolved.
+// Lifecycle hooks that only tag images and the recreate mid hook are not converted.
+func LifecycleHookJobs(dc *osappsv1.DeploymentConfig, deployment *appsv1.Deployment) (pre []*batchv1.Job, post []*batchv1.Job, err error) {
+	preHook, postHook := lifecycleHooks(dc)
+
+	if preHook != nil && preHook.ExecNewPod != nil {
+		job, err := convertLifecycleHook(dc, deployment, "pre", preHook)
+		if err != nil {
+			return nil, nil, err
+		}
+		pre = append(pre, job)
+	}
+	if postHook != nil && postHook.ExecNewPod != nil {
+		job, err := convertLifecycleHook(dc, deployment, "post", postHook)
+		if err != nil {
+			return nil, nil, err
+		}
+		post = append(post, job)
+	}
+	return pre, post, nil
+}
+
+// LifecycleHookInitContainer converts the pre lifecycle hook of the deployment config into an init container
+// prepended to the init containers of the deployment pod template. Unlike the hook, the init container runs
+// before every pod start, not once per rollout. It returns false when there is no pre hook to convert.
+func LifecycleHookInitContainer(dc *osappsv1.DeploymentConfig, deployment *appsv1.Deployment) (bool, error) {
+	preHook, _ := lifecycleHooks(dc)
+	if preHook == nil || preHook.ExecNewPod == nil {
+		return false, nil
+	}
+	container, err := hookContainer(dc, &deployment.Spec.Template, "pre", preHook.ExecNewPod)
+	if err != nil {
+		return false, err
+	}
+	container.Name = "hook-pre"
+	podSpec := &deployment.Spec.Template.Spec
+	podSpec.InitContainers = append([]corev1.Container{*container}, podSpec.InitContainers...)
+	return true, nil
+}
+
+// lifecycleHooks returns the pre and post lifecycle hooks of the deployment config strategy.
+func lifecycleHooks(dc *osappsv1.DeploymentConfig) (pre, post *osappsv1.LifecycleHook) {
+	switch params := dc.Spec.Strategy; {
+	case params.Type == osappsv1.DeploymentStrategyTypeRecreate && params.RecreateParams != nil:
+		return params.RecreateParams.Pre, params.RecreateParams.Post
+	case params.Type != osappsv1.DeploymentStrategyTypeRecreate && params.RollingParams != nil:
+		return params.RollingParams.Pre, params.RollingParams.Post
+	}
+	return nil, nil
+}
+
+// hookContainer returns a copy of the hook container running the hook command, with only the hook volumes
+// mounted and without the probes, lifecycle handlers and ports.
+func hookContainer(dc *osappsv1.DeploymentConfig, template *corev1.PodTemplateSpec, stage string, exec *osappsv1.ExecNewPodHook) (*corev1.Container, error) {
+	var container *corev1.Container
+	for i := range template.Spec.Containers {
+		if template.Spec.Containers[i].Name == exec.ContainerName {
+			container = template.Spec.Containers[i].DeepCopy()
+			break
+		}
+	}
+	if container == nil {
+		return nil, fmt.Errorf("%s lifecycle hook of deployment config %q references unknown container %q", stage,
+			dc.Namespace+"/"+dc.Name, exec.ContainerName)
+	}
+
+	container.Command = exec.Command
+	container.Args = nil
+	container.Env = append(container.Env, exec.Env...)
+	container.LivenessProbe = nil
+	container.ReadinessProbe = nil
+	container.Lifecycle = nil
+	container.Ports = nil
+
+	mounts := []corev1.VolumeMount{}
+	for _, mount := range container.VolumeMounts {
+		if hasString(exec.Volumes, mount.Name) {
+			mounts = append(mounts, mount)
+		}
+	}
+	container.VolumeMounts = mounts
+	return container, nil
+}
+
+// convertLifecycleHook converts the lifecycle hook into a job running the hook command in a copy
+// of the hook container, the same way the deployment config controller runs the hook pod.
+func convertLifecycleHook(dc *osappsv1.DeploymentConfig, deployment *appsv1.Deployment, stage string, hook *osappsv1.LifecycleHook) (*batchv1.Job, error) {
+	exec := hook.ExecNewPod
+	template := &deployment.Spec.Template
+
+	container, err := hookContainer(dc, template, stage, exec)
+	if err != nil {
+		return nil, err
+	}
+
+	podSpec := template.Spec.DeepCopy()
+	podSpec.InitContainers = nil
+	podSpec.RestartPolicy = corev1.RestartPolicyNever
+	podSpec.ActiveDeadlineSeconds = dc.Spec.Strategy.ActiveDeadlineSeconds
+
+	// Only the volumes listed in the hook are mounted into the hook container
+	podSpec.Volumes = nil
+	for _, volume := range template.Spec.Volumes {
+		if hasString(exec.Volumes, volume.Name) {
+			podSpec.Volumes = append(podSpec.Volumes, volume)
+		}
+	}
+	podSpec.Containers = []corev1.Container{*container}
+
+	labels := map[string]string{
+		DeploymentConfigNameLabel: dc.Name,
+		HookStageLabel:            stage,
+	}
+	annotations := ProvenanceAnnotations(deployment)
+	annotations[HookFailurePolicyAnnotation] = string(hook.FailurePolicy)
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        dc.Name + "-hook-" + stage,
+			Namespace:   dc.Namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       *podSpec,
+			},
+		},
+	}
+
+	// Only the retry policy retries failed hooks
+	if hook.FailurePolicy != osappsv1.LifecycleHookFailurePolicyRetry {
+		backoffLimit := int32(0)
+		job.Spec.BackoffLimit = &backoffLimit
+	}
+
+	return job, nil
+}
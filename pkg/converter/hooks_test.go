@@ -0,0 +1,205 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	osappsv1 "github.com/openshift/api/apps/v1"
+)
+
+func TestHasLifecycleHooks(t *testing.T) {
+	hook := &osappsv1.LifecycleHook{FailurePolicy: osappsv1.LifecycleHookFailurePolicyAbort}
+
+	tests := []struct {
+		name         string
+		strategy     osappsv1.DeploymentStrategy
+		expected     bool
+		expectedPost bool
+		expectedMid  bool
+	}{
+		{
+			name:     "no hooks",
+			strategy: osappsv1.DeploymentStrategy{Type: osappsv1.DeploymentStrategyTypeRolling, RollingParams: &osappsv1.RollingDeploymentStrategyParams{}},
+		},
+		{
+			name: "rolling strategy pre hook",
+			strategy: osappsv1.DeploymentStrategy{
+				Type:          osappsv1.DeploymentStrategyTypeRolling,
+				RollingParams: &osappsv1.RollingDeploymentStrategyParams{Pre: hook},
+			},
+			expected: true,
+		},
+		{
+			name: "rolling strategy post hook",
+			strategy: osappsv1.DeploymentStrategy{
+				Type:          osappsv1.DeploymentStrategyTypeRolling,
+				RollingParams: &osappsv1.RollingDeploymentStrategyParams{Post: hook},
+			},
+			expected:     true,
+			expectedPost: true,
+		},
+		{
+			name: "recreate strategy mid hook",
+			strategy: osappsv1.DeploymentStrategy{
+				Type:           osappsv1.DeploymentStrategyTypeRecreate,
+				RecreateParams: &osappsv1.RecreateDeploymentStrategyParams{Mid: hook},
+			},
+			expected:    true,
+			expectedMid: true,
+		},
+		{
+			name: "recreate hooks of rolling strategy",
+			strategy: osappsv1.DeploymentStrategy{
+				Type:           osappsv1.DeploymentStrategyTypeRolling,
+				RollingParams:  &osappsv1.RollingDeploymentStrategyParams{},
+				RecreateParams: &osappsv1.RecreateDeploymentStrategyParams{Pre: hook, Mid: hook, Post: hook},
+			},
+		},
+		{
+			name: "rolling hooks of recreate strategy",
+			strategy: osappsv1.DeploymentStrategy{
+				Type:           osappsv1.DeploymentStrategyTypeRecreate,
+				RecreateParams: &osappsv1.RecreateDeploymentStrategyParams{},
+				RollingParams:  &osappsv1.RollingDeploymentStrategyParams{Pre: hook, Post: hook},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dc := newDeploymentConfig()
+			dc.Spec.Strategy = test.strategy
+			if hooks := HasLifecycleHooks(dc); hooks != test.expected {
+				t.Errorf("expected lifecycle hooks %t, got %t", test.expected, hooks)
+			}
+			if post := HasPostLifecycleHook(dc); post != test.expectedPost {
+				t.Errorf("expected post lifecycle hook %t, got %t", test.expectedPost, post)
+			}
+			if mid := HasMidLifecycleHook(dc); mid != test.expectedMid {
+				t.Errorf("expected mid lifecycle hook %t, got %t", test.expectedMid, mid)
+			}
+		})
+	}
+}
+
+func TestLifecycleHookInitContainer(t *testing.T) {
+	preHook := &osappsv1.LifecycleHook{
+		FailurePolicy: osappsv1.LifecycleHookFailurePolicyAbort,
+		ExecNewPod: &osappsv1.ExecNewPodHook{
+			ContainerName: "web",
+			Command:       []string{"/migrate"},
+			Env:           []corev1.EnvVar{{Name: "STAGE", Value: "pre"}},
+			Volumes:       []string{"config"},
+		},
+	}
+
+	tests := []struct {
+		name              string
+		strategy          osappsv1.DeploymentStrategy
+		expectedConverted bool
+		expectedErr       string
+	}{
+		{
+			name: "rolling strategy pre hook",
+			strategy: osappsv1.DeploymentStrategy{
+				Type:          osappsv1.DeploymentStrategyTypeRolling,
+				RollingParams: &osappsv1.RollingDeploymentStrategyParams{Pre: preHook},
+			},
+			expectedConverted: true,
+		},
+		{
+			name: "recreate strategy pre hook",
+			strategy: osappsv1.DeploymentStrategy{
+				Type:           osappsv1.DeploymentStrategyTypeRecreate,
+				RecreateParams: &osappsv1.RecreateDeploymentStrategyParams{Pre: preHook},
+			},
+			expectedConverted: true,
+		},
+		{
+			name: "post hook only",
+			strategy: osappsv1.DeploymentStrategy{
+				Type:          osappsv1.DeploymentStrategyTypeRolling,
+				RollingParams: &osappsv1.RollingDeploymentStrategyParams{Post: preHook},
+			},
+		},
+		{
+			name: "pre hook tagging images",
+			strategy: osappsv1.DeploymentStrategy{
+				Type: osappsv1.DeploymentStrategyTypeRolling,
+				RollingParams: &osappsv1.RollingDeploymentStrategyParams{
+					Pre: &osappsv1.LifecycleHook{TagImages: []osappsv1.TagImageHook{{ContainerName: "web"}}},
+				},
+			},
+		},
+		{
+			name: "pre hook of unknown container",
+			strategy: osappsv1.DeploymentStrategy{
+				Type: osappsv1.DeploymentStrategyTypeRolling,
+				RollingParams: &osappsv1.RollingDeploymentStrategyParams{
+					Pre: &osappsv1.LifecycleHook{ExecNewPod: &osappsv1.ExecNewPodHook{ContainerName: "db"}},
+				},
+			},
+			expectedErr: `pre lifecycle hook of deployment config "demo/web" references unknown container "db"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dc := newDeploymentConfig()
+			dc.Spec.Strategy = test.strategy
+			dc.Spec.Template.Spec.InitContainers = []corev1.Container{{Name: "setup", Image: "busybox"}}
+			dc.Spec.Template.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{
+				{Name: "config", MountPath: "/etc/web"},
+				{Name: "data", MountPath: "/var/web"},
+			}
+			dc.Spec.Template.Spec.Containers[0].ReadinessProbe = &corev1.Probe{}
+			deployment, err := Convert(dc, Options{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			converted, err := LifecycleHookInitContainer(dc, deployment)
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if converted != test.expectedConverted {
+				t.Fatalf("expected converted %t, got %t", test.expectedConverted, converted)
+			}
+			initContainers := deployment.Spec.Template.Spec.InitContainers
+			if !converted {
+				if len(initContainers) != 1 {
+					t.Errorf("expected the init containers unchanged, got %#v", initContainers)
+				}
+				return
+			}
+			if len(initContainers) != 2 || initContainers[0].Name != "hook-pre" || initContainers[1].Name != "setup" {
+				t.Fatalf("expected the hook init container before the existing init containers, got %#v", initContainers)
+			}
+			hook := initContainers[0]
+			if hook.Image != "nginx:1" || !reflect.DeepEqual(hook.Command, []string{"/migrate"}) {
+				t.Errorf("expected the hook command in the hook container image, got %q %v", hook.Image, hook.Command)
+			}
+			if !reflect.DeepEqual(hook.Env, []corev1.EnvVar{{Name: "STAGE", Value: "pre"}}) {
+				t.Errorf("expected the hook environment, got %v", hook.Env)
+			}
+			if !reflect.DeepEqual(hook.VolumeMounts, []corev1.VolumeMount{{Name: "config", MountPath: "/etc/web"}}) {
+				t.Errorf("expected only the hook volumes mounted, got %v", hook.VolumeMounts)
+			}
+			if hook.ReadinessProbe != nil {
+				t.Errorf("expected no probes in the hook init container")
+			}
+			if len(deployment.Spec.Template.Spec.Containers[0].VolumeMounts) != 2 {
+				t.Errorf("expected the application container not to be modified")
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	osappsv1 "github.com/openshift/api/apps/v1"
+	imagev1client "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
+)
+
+// ImageResolver resolves the image change trigger source into an image pull spec.
+type ImageResolver interface {
+	ResolveImage(namespace string, from corev1.ObjectReference) (string, error)
+}
+
+const (
+	// ImageResolutionLastTriggered uses the image recorded by the trigger and falls back to the image
+	// resolver when no image was triggered yet.
+	ImageResolutionLastTriggered = "lastTriggered"
+	// ImageResolutionImageStream always resolves the current image using the image resolver.
+	ImageResolutionImageStream = "imagestream"
+	// ImageResolutionKeep keeps the container images as they are in the pod template.
+	ImageResolutionKeep = "keep"
+)
+
+const (
+	// ImageAutomationNone writes no image automation annotations.
+	ImageAutomationNone = "none"
+	// ImageAutomationArgoCD writes the Argo CD Image Updater annotations.
+	ImageAutomationArgoCD = "argocd"
+
+	// ArgoCDImageListAnnotation lists the images the Argo CD Image Updater updates, in the alias=image form.
+	ArgoCDImageListAnnotation = "argocd-image-updater.argoproj.io/image-list"
+)
+
+// ImageStreamClient is the part of the image client used to resolve the images.
+type ImageStreamClient interface {
+	imagev1client.ImageStreamTagsGetter
+	imagev1client.ImageStreamImagesGetter
+}
+
+// NewImageStreamTagResolver returns an image resolver that looks up the image stream tags and images
+// using the given client. The DockerImage references are resolved to their name.
+func NewImageStreamTagResolver(client ImageStreamClient) ImageResolver {
+	return &imageStreamTagResolver{client: client}
+}
+
+type imageStreamTagResolver struct {
+	client ImageStreamClient
+}
+
+func (r *imageStreamTagResolver) ResolveImage(namespace string, from corev1.ObjectReference) (string, error) {
+	var image string
+	switch from.Kind {
+	case "DockerImage":
+		return from.Name, nil
+	case "ImageStreamTag":
+		tag, err := r.client.ImageStreamTags(namespace).Get(from.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return "", fmt.Errorf("image stream tag %q not found", namespace+"/"+from.Name)
+		}
+		if err != nil {
+			return "", err
+		}
+		image = tag.Image.DockerImageReference
+	case "ImageStreamImage":
+		streamImage, err := r.client.ImageStreamImages(namespace).Get(from.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return "", fmt.Errorf("image stream image %q not found", namespace+"/"+from.Name)
+		}
+		if err != nil {
+			return "", err
+		}
+		image = streamImage.Image.DockerImageReference
+	default:
+		return "", fmt.Errorf("unsupported image change trigger source kind %q", from.Kind)
+	}
+	if len(image) == 0 {
+		return "", fmt.Errorf("%s %q has no image", from.Kind, namespace+"/"+from.Name)
+	}
+	return image, nil
+}
+
+// resolveTriggerImages sets the images resolved from the deployment config image change triggers
+// into the matching containers and init containers of the pod template.
+// By default, the last triggered image recorded in the trigger is preferred, if it is not set, the image
+// resolver is used to look the image up. The image resolution option changes which image is used.
+// Without the image resolver, e.g. when converting files, the containers keep the pod template images.
+func resolveTriggerImages(dc *osappsv1.DeploymentConfig, template *corev1.PodTemplateSpec, opts Options) error {
+	containers := templateContainers(template)
+	for _, trigger := range dc.Spec.Triggers {
+		if trigger.Type != osappsv1.DeploymentTriggerOnImageChange || trigger.ImageChangeParams == nil {
+			continue
+		}
+		params := trigger.ImageChangeParams
+		for _, name := range params.ContainerNames {
+			if !hasContainer(containers, name) {
+				return fmt.Errorf("image change trigger from %s %q in deployment config %q references unknown container %q",
+					params.From.Kind, params.From.Name, dc.Namespace+"/"+dc.Name, name)
+			}
+		}
+
+		if opts.ImageResolution == ImageResolutionKeep {
+			opts.Report.Droppedf("ImageChange trigger from %s %q (container images kept)", params.From.Kind, params.From.Name)
+			continue
+		}
+
+		image := params.LastTriggeredImage
+		if opts.ImageResolution == ImageResolutionImageStream {
+			image = ""
+		}
+		var resolveErr error
+		if len(image) == 0 {
+			namespace := params.From.Namespace
+			if len(namespace) == 0 {
+				namespace = dc.Namespace
+			}
+			if opts.ImageResolver == nil {
+				resolveErr = fmt.Errorf("no image resolver available to resolve %s %q", params.From.Kind, params.From.Name)
+			} else {
+				image, resolveErr = opts.ImageResolver.ResolveImage(namespace, params.From)
+			}
+		}
+
+		for _, container := range containers {
+			if !hasString(params.ContainerNames, container.Name) {
+				continue
+			}
+			// Without the image resolver the containers keep the pod template image, the resolver errors
+			// are not ignored as the pod template image is likely outdated
+			if resolveErr != nil && (opts.ImageResolver != nil || len(strings.TrimSpace(container.Image)) == 0) {
+				return fmt.Errorf("unable to resolve image for container %q in deployment config %q: %v", container.Name,
+					dc.Namespace+"/"+dc.Name, resolveErr)
+			}
+			if resolveErr != nil {
+				opts.Report.Droppedf("ImageChange trigger from %s %q for container %q (not resolved, the pod template image %q is kept): %v",
+					params.From.Kind, params.From.Name, container.Name, container.Image, resolveErr)
+				continue
+			}
+			container.Image = image
+			opts.Report.Approximatedf("ImageChange trigger from %s %q resolved to image %q for container %q (automatic image updates are lost)",
+				params.From.Kind, params.From.Name, image, container.Name)
+		}
+		if isCrossNamespaceTrigger(dc, params) {
+			opts.Report.Approximatedf("ImageChange trigger from %s %q in namespace %q (the image is hard-coded, changes in the other namespace are not rolled out)",
+				params.From.Kind, params.From.Name, params.From.Namespace)
+		}
+	}
+
+	for _, container := range containers {
+		if len(strings.TrimSpace(container.Image)) == 0 {
+			return fmt.Errorf("container %q in deployment config %q has no image", container.Name, dc.Namespace+"/"+dc.Name)
+		}
+	}
+
+	return nil
+}
+
+// CrossNamespaceImageTriggers returns the image change trigger sources of the deployment config that are
+// in other namespaces than the deployment config.
+func CrossNamespaceImageTriggers(dc *osappsv1.DeploymentConfig) []corev1.ObjectReference {
+	result := []corev1.ObjectReference{}
+	for _, trigger := range dc.Spec.Triggers {
+		if trigger.Type == osappsv1.DeploymentTriggerOnImageChange && trigger.ImageChangeParams != nil &&
+			isCrossNamespaceTrigger(dc, trigger.ImageChangeParams) {
+			result = append(result, trigger.ImageChangeParams.From)
+		}
+	}
+	return result
+}
+
+func isCrossNamespaceTrigger(dc *osappsv1.DeploymentConfig, params *osappsv1.DeploymentTriggerImageChangeParams) bool {
+	return len(params.From.Namespace) > 0 && params.From.Namespace != dc.Namespace
+}
+
+// setArgoCDImageUpdaterAnnotations documents the automatic image change triggers of the deployment config as
+// Argo CD Image Updater annotations on the deployment. Every container updated by a trigger gets an image list
+// entry aliased by the container name, with the tag of the source image stream tag as the allowed tag.
+// The annotations are only a starting point, Argo CD Image Updater reads them from the Argo CD application
+// and watches the image repository instead of the image stream.
+func setArgoCDImageUpdaterAnnotations(dc *osappsv1.DeploymentConfig, deployment *appsv1.Deployment, opts Options) {
+	images := []string{}
+	for _, trigger := range dc.Spec.Triggers {
+		if trigger.Type != osappsv1.DeploymentTriggerOnImageChange || trigger.ImageChangeParams == nil || !trigger.ImageChangeParams.Automatic {
+			continue
+		}
+		params := trigger.ImageChangeParams
+		for _, container := range templateContainers(&deployment.Spec.Template) {
+			if !hasString(params.ContainerNames, container.Name) {
+				continue
+			}
+			images = append(images, container.Name+"="+imageRepository(container.Image))
+			if params.From.Kind != "ImageStreamTag" {
+				continue
+			}
+			if i := strings.LastIndex(params.From.Name, ":"); i >= 0 {
+				deployment.Annotations["argocd-image-updater.argoproj.io/"+container.Name+".allow-tags"] = "regexp:^" + regexp.QuoteMeta(params.From.Name[i+1:]) + "$"
+			}
+		}
+		opts.Report.Approximatedf("automatic ImageChange trigger from %s %q as %q annotation (requires Argo CD Image Updater)",
+			params.From.Kind, params.From.Name, ArgoCDImageListAnnotation)
+	}
+	if len(images) > 0 {
+		deployment.Annotations[ArgoCDImageListAnnotation] = strings.Join(images, ",")
+	}
+}
+
+// imageRepository returns the image pull spec without the tag and digest.
+func imageRepository(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	// The registry host may have a port, the tag follows the last path segment
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// imageReferencePattern matches the image pull specs in the [registry[:port]/]path[:tag][@digest] form.
+var imageReferencePattern = regexp.MustCompile(`^(?:[a-zA-Z0-9.-]+(?::[0-9]+)?/)?` +
+	`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+	`(?::[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?` +
+	`(?:@[a-zA-Z][a-zA-Z0-9]*(?:[-_+.][a-zA-Z][a-zA-Z0-9]*)*:[0-9a-fA-F]{32,})?$`)
+
+// VerifyImages checks that the images of all containers in the deployment pod template are well-formed
+// image pull specs. It returns warnings for the images referenced by a floating tag (latest or no tag),
+// which may change between the migration and the next pod start.
+func VerifyImages(deployment *appsv1.Deployment) ([]string, error) {
+	warnings := []string{}
+	for _, container := range templateContainers(&deployment.Spec.Template) {
+		if len(container.Image) == 0 {
+			return nil, fmt.Errorf("container %q in deployment %q has no image", container.Name, deployment.Namespace+"/"+deployment.Name)
+		}
+		if !imageReferencePattern.MatchString(container.Image) {
+			return nil, fmt.Errorf("container %q in deployment %q has invalid image %q", container.Name, deployment.Namespace+"/"+deployment.Name, container.Image)
+		}
+		if strings.Contains(container.Image, "@") {
+			continue
+		}
+		repository := imageRepository(container.Image)
+		if tag := strings.TrimPrefix(container.Image[len(repository):], ":"); len(tag) == 0 || tag == "latest" {
+			warnings = append(warnings, fmt.Sprintf("container %q image %q uses a floating tag", container.Name, container.Image))
+		}
+	}
+	return warnings, nil
+}
+
+// templateContainers returns the init containers and containers of the pod template.
+func templateContainers(template *corev1.PodTemplateSpec) []*corev1.Container {
+	result := []*corev1.Container{}
+	for i := range template.Spec.InitContainers {
+		result = append(result, &template.Spec.InitContainers[i])
+	}
+	for i := range template.Spec.Containers {
+		result = append(result, &template.Spec.Containers[i])
+	}
+	return result
+}
+
+func hasContainer(containers []*corev1.Container, name string) bool {
+	for _, container := range containers {
+		if container.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasString(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,404 @@
+package converter
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clienttesting "k8s.io/client-go/testing"
+
+	osappsv1 "github.com/openshift/api/apps/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+	fakeimagev1 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1/fake"
+)
+
+// fakeImageResolver resolves the images from the map indexed by namespace/name of the trigger source.
+type fakeImageResolver map[string]string
+
+func (r fakeImageResolver) ResolveImage(namespace string, from corev1.ObjectReference) (string, error) {
+	image, ok := r[namespace+"/"+from.Name]
+	if !ok {
+		return "", fmt.Errorf("%s %q not found", from.Kind, namespace+"/"+from.Name)
+	}
+	return image, nil
+}
+
+// imageChangeTrigger returns the image change trigger of the containers from the image stream tag.
+func imageChangeTrigger(from, lastTriggeredImage string, containers ...string) osappsv1.DeploymentTriggerPolicy {
+	return osappsv1.DeploymentTriggerPolicy{
+		Type: osappsv1.DeploymentTriggerOnImageChange,
+		ImageChangeParams: &osappsv1.DeploymentTriggerImageChangeParams{
+			Automatic:          true,
+			ContainerNames:     containers,
+			From:               corev1.ObjectReference{Kind: "ImageStreamTag", Name: from},
+			LastTriggeredImage: lastTriggeredImage,
+		},
+	}
+}
+
+func TestResolveTriggerImages(t *testing.T) {
+	resolver := fakeImageResolver{
+		"demo/web:latest":   "registry/demo/web@sha256:2",
+		"shared/base:1":     "registry/shared/base@sha256:3",
+		"demo/migrate:v1.0": "registry/demo/migrate@sha256:4",
+	}
+
+	tests := []struct {
+		name     string
+		triggers osappsv1.DeploymentTriggerPolicies
+		template func(*corev1.PodTemplateSpec)
+		opts     Options
+		// expectedImages are the expected images of the init containers and containers.
+		expectedImages []string
+		expectedErr    string
+	}{
+		{
+			name:           "last triggered image",
+			triggers:       osappsv1.DeploymentTriggerPolicies{imageChangeTrigger("web:latest", "registry/demo/web@sha256:1", "web")},
+			opts:           Options{ImageResolver: resolver},
+			expectedImages: []string{"registry/demo/web@sha256:1"},
+		},
+		{
+			name:           "resolved image without last triggered image",
+			triggers:       osappsv1.DeploymentTriggerPolicies{imageChangeTrigger("web:latest", "", "web")},
+			opts:           Options{ImageResolver: resolver},
+			expectedImages: []string{"registry/demo/web@sha256:2"},
+		},
+		{
+			name:           "image stream resolution ignores the last triggered image",
+			triggers:       osappsv1.DeploymentTriggerPolicies{imageChangeTrigger("web:latest", "registry/demo/web@sha256:1", "web")},
+			opts:           Options{ImageResolver: resolver, ImageResolution: ImageResolutionImageStream},
+			expectedImages: []string{"registry/demo/web@sha256:2"},
+		},
+		{
+			name:           "keep resolution keeps the container image",
+			triggers:       osappsv1.DeploymentTriggerPolicies{imageChangeTrigger("web:latest", "registry/demo/web@sha256:1", "web")},
+			opts:           Options{ImageResolver: resolver, ImageResolution: ImageResolutionKeep},
+			expectedImages: []string{"nginx:1"},
+		},
+		{
+			name: "trigger source in other namespace",
+			triggers: func() osappsv1.DeploymentTriggerPolicies {
+				trigger := imageChangeTrigger("base:1", "", "web")
+				trigger.ImageChangeParams.From.Namespace = "shared"
+				return osappsv1.DeploymentTriggerPolicies{trigger}
+			}(),
+			opts:           Options{ImageResolver: resolver},
+			expectedImages: []string{"registry/shared/base@sha256:3"},
+		},
+		{
+			name:     "init container",
+			triggers: osappsv1.DeploymentTriggerPolicies{imageChangeTrigger("migrate:v1.0", "", "migrate")},
+			template: func(template *corev1.PodTemplateSpec) {
+				template.Spec.InitContainers = []corev1.Container{{Name: "migrate", Image: " "}}
+			},
+			opts:           Options{ImageResolver: resolver},
+			expectedImages: []string{"registry/demo/migrate@sha256:4", "nginx:1"},
+		},
+		{
+			name:        "unknown container",
+			triggers:    osappsv1.DeploymentTriggerPolicies{imageChangeTrigger("web:latest", "", "db")},
+			opts:        Options{ImageResolver: resolver},
+			expectedErr: `image change trigger from ImageStreamTag "web:latest" in deployment config "demo/web" references unknown container "db"`,
+		},
+		{
+			name:     "unresolved image of container without image",
+			triggers: osappsv1.DeploymentTriggerPolicies{imageChangeTrigger("web:missing", "", "web")},
+			template: func(template *corev1.PodTemplateSpec) {
+				template.Spec.Containers[0].Image = ""
+			},
+			opts:        Options{ImageResolver: resolver},
+			expectedErr: `unable to resolve image for container "web" in deployment config "demo/web": ImageStreamTag "demo/web:missing" not found`,
+		},
+		{
+			name:        "unresolved image of container with image",
+			triggers:    osappsv1.DeploymentTriggerPolicies{imageChangeTrigger("web:missing", "", "web")},
+			opts:        Options{ImageResolver: resolver},
+			expectedErr: `unable to resolve image for container "web" in deployment config "demo/web": ImageStreamTag "demo/web:missing" not found`,
+		},
+		{
+			name:           "container keeps its image without image resolver",
+			triggers:       osappsv1.DeploymentTriggerPolicies{imageChangeTrigger("web:latest", "", "web")},
+			expectedImages: []string{"nginx:1"},
+		},
+		{
+			name:     "placeholder image without image resolver",
+			triggers: osappsv1.DeploymentTriggerPolicies{imageChangeTrigger("web:latest", "", "web")},
+			template: func(template *corev1.PodTemplateSpec) {
+				template.Spec.Containers[0].Image = " "
+			},
+			expectedErr: `unable to resolve image for container "web" in deployment config "demo/web": no image resolver available`,
+		},
+		{
+			name: "container with placeholder image and without trigger",
+			template: func(template *corev1.PodTemplateSpec) {
+				template.Spec.Containers[0].Image = " "
+			},
+			expectedErr: `container "web" in deployment config "demo/web" has no image`,
+		},
+		{
+			name: "container without image and trigger",
+			template: func(template *corev1.PodTemplateSpec) {
+				template.Spec.Containers[0].Image = ""
+			},
+			expectedErr: `container "web" in deployment config "demo/web" has no image`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dc := newDeploymentConfig()
+			dc.Spec.Triggers = test.triggers
+			template := dc.Spec.Template.DeepCopy()
+			if test.template != nil {
+				test.template(template)
+			}
+			err := resolveTriggerImages(dc, template, test.opts)
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			images := []string{}
+			for _, container := range templateContainers(template) {
+				images = append(images, container.Image)
+			}
+			if !reflect.DeepEqual(images, test.expectedImages) {
+				t.Errorf("expected images %v, got %v", test.expectedImages, images)
+			}
+		})
+	}
+}
+
+func TestImageStreamTagResolver(t *testing.T) {
+	client := &fakeimagev1.FakeImageV1{Fake: &clienttesting.Fake{}}
+	client.AddReactor("get", "imagestreamtags", func(action clienttesting.Action) (bool, kruntime.Object, error) {
+		get := action.(clienttesting.GetAction)
+		if get.GetNamespace() != "demo" || get.GetName() != "web:latest" {
+			return true, nil, errors.NewNotFound(schema.GroupResource{Group: "image.openshift.io", Resource: "imagestreamtags"}, get.GetName())
+		}
+		return true, &imagev1.ImageStreamTag{
+			ObjectMeta: metav1.ObjectMeta{Name: "web:latest", Namespace: "demo"},
+			Image:      imagev1.Image{DockerImageReference: "registry/demo/web@sha256:1"},
+		}, nil
+	})
+	client.AddReactor("get", "imagestreamimages", func(action clienttesting.Action) (bool, kruntime.Object, error) {
+		get := action.(clienttesting.GetAction)
+		if get.GetNamespace() != "demo" || get.GetName() != "web@sha256:2" {
+			return true, nil, errors.NewNotFound(schema.GroupResource{Group: "image.openshift.io", Resource: "imagestreamimages"}, get.GetName())
+		}
+		return true, &imagev1.ImageStreamImage{
+			ObjectMeta: metav1.ObjectMeta{Name: "web@sha256:2", Namespace: "demo"},
+			Image:      imagev1.Image{DockerImageReference: "registry/demo/web@sha256:2"},
+		}, nil
+	})
+	resolver := NewImageStreamTagResolver(client)
+
+	tests := []struct {
+		name          string
+		from          corev1.ObjectReference
+		expectedImage string
+		expectedErr   string
+	}{
+		{
+			name:          "image stream tag",
+			from:          corev1.ObjectReference{Kind: "ImageStreamTag", Name: "web:latest"},
+			expectedImage: "registry/demo/web@sha256:1",
+		},
+		{
+			name:        "missing image stream tag",
+			from:        corev1.ObjectReference{Kind: "ImageStreamTag", Name: "web:missing"},
+			expectedErr: `image stream tag "demo/web:missing" not found`,
+		},
+		{
+			name:          "image stream image",
+			from:          corev1.ObjectReference{Kind: "ImageStreamImage", Name: "web@sha256:2"},
+			expectedImage: "registry/demo/web@sha256:2",
+		},
+		{
+			name:        "missing image stream image",
+			from:        corev1.ObjectReference{Kind: "ImageStreamImage", Name: "web@sha256:3"},
+			expectedErr: `image stream image "demo/web@sha256:3" not found`,
+		},
+		{
+			name:          "docker image",
+			from:          corev1.ObjectReference{Kind: "DockerImage", Name: "nginx:1"},
+			expectedImage: "nginx:1",
+		},
+		{
+			name:        "unsupported kind",
+			from:        corev1.ObjectReference{Kind: "ImageStream", Name: "web"},
+			expectedErr: `unsupported image change trigger source kind "ImageStream"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			image, err := resolver.ResolveImage("demo", test.from)
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if image != test.expectedImage {
+				t.Errorf("expected image %q, got %q", test.expectedImage, image)
+			}
+		})
+	}
+}
+
+func TestArgoCDImageUpdaterAnnotations(t *testing.T) {
+	tests := []struct {
+		name                string
+		trigger             osappsv1.DeploymentTriggerPolicy
+		image               string
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:    "automatic trigger",
+			trigger: imageChangeTrigger("web:v1.2", "", "web"),
+			image:   "registry/demo/web:v1.2",
+			expectedAnnotations: map[string]string{
+				ArgoCDImageListAnnotation:                         "web=registry/demo/web",
+				"argocd-image-updater.argoproj.io/web.allow-tags": `regexp:^v1\.2$`,
+			},
+		},
+		{
+			name: "non-automatic trigger",
+			trigger: func() osappsv1.DeploymentTriggerPolicy {
+				trigger := imageChangeTrigger("web:v1.2", "", "web")
+				trigger.ImageChangeParams.Automatic = false
+				return trigger
+			}(),
+			image:               "registry/demo/web:v1.2",
+			expectedAnnotations: map[string]string{},
+		},
+		{
+			name:    "registry with port and digest",
+			trigger: imageChangeTrigger("web:latest", "", "web"),
+			image:   "registry:5000/demo/web@sha256:1",
+			expectedAnnotations: map[string]string{
+				ArgoCDImageListAnnotation:                         "web=registry:5000/demo/web",
+				"argocd-image-updater.argoproj.io/web.allow-tags": "regexp:^latest$",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dc := newDeploymentConfig()
+			dc.Spec.Triggers = osappsv1.DeploymentTriggerPolicies{test.trigger}
+			dc.Spec.Template.Spec.Containers[0].Image = test.image
+			deployment, err := Convert(dc, Options{ImageResolution: ImageResolutionKeep, ImageAutomation: ImageAutomationArgoCD})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			annotations := map[string]string{}
+			for key, value := range deployment.Annotations {
+				if strings.HasPrefix(key, "argocd-image-updater.argoproj.io/") {
+					annotations[key] = value
+				}
+			}
+			if !reflect.DeepEqual(annotations, test.expectedAnnotations) {
+				t.Errorf("expected annotations %v, got %v", test.expectedAnnotations, annotations)
+			}
+		})
+	}
+}
+
+func TestCrossNamespaceImageTriggers(t *testing.T) {
+	shared := imageChangeTrigger("base:1", "", "web")
+	shared.ImageChangeParams.From.Namespace = "shared"
+	sameNamespace := imageChangeTrigger("web:latest", "", "web")
+	sameNamespace.ImageChangeParams.From.Namespace = "demo"
+
+	dc := newDeploymentConfig()
+	dc.Spec.Triggers = osappsv1.DeploymentTriggerPolicies{
+		{Type: osappsv1.DeploymentTriggerOnConfigChange},
+		imageChangeTrigger("web:latest", "", "web"),
+		sameNamespace,
+		shared,
+	}
+	expected := []corev1.ObjectReference{{Kind: "ImageStreamTag", Name: "base:1", Namespace: "shared"}}
+	if sources := CrossNamespaceImageTriggers(dc); !reflect.DeepEqual(sources, expected) {
+		t.Errorf("expected trigger sources %v, got %v", expected, sources)
+	}
+}
+
+func TestVerifyImages(t *testing.T) {
+	tests := []struct {
+		name             string
+		image            string
+		expectedWarnings []string
+		expectedErr      string
+	}{
+		{
+			name:             "valid",
+			image:            "registry.example.com:5000/demo/web:v1.2",
+			expectedWarnings: []string{},
+		},
+		{
+			name:             "digest",
+			image:            "registry/demo/web@sha256:0123456789abcdef0123456789abcdef",
+			expectedWarnings: []string{},
+		},
+		{
+			name:        "empty",
+			image:       "",
+			expectedErr: `container "web" in deployment "demo/web" has no image`,
+		},
+		{
+			name:        "invalid",
+			image:       "Registry/Demo Web:1",
+			expectedErr: `container "web" in deployment "demo/web" has invalid image "Registry/Demo Web:1"`,
+		},
+		{
+			name:             "latest",
+			image:            "nginx:latest",
+			expectedWarnings: []string{`container "web" image "nginx:latest" uses a floating tag`},
+		},
+		{
+			name:             "untagged",
+			image:            "registry:5000/demo/web",
+			expectedWarnings: []string{`container "web" image "registry:5000/demo/web" uses a floating tag`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dc := newDeploymentConfig()
+			deployment, err := Convert(dc, Options{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			deployment.Spec.Template.Spec.Containers[0].Image = test.image
+			warnings, err := VerifyImages(deployment)
+			if len(test.expectedErr) > 0 {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Fatalf("expected error %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(warnings, test.expectedWarnings) {
+				t.Errorf("expected warnings %v, got %v", test.expectedWarnings, warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package converter
+
+import "fmt"
+
+// Report summarizes how the parts of the deployment config were migrated.
+type Report struct {
+	DeploymentConfig string `json:"deploymentConfig"`
+
+	// Converted lists the parts converted without any loss.
+	Converted []string `json:"converted,omitempty"`
+	// Approximated lists the parts converted with a change in behavior.
+	Approximated []string `json:"approximated,omitempty"`
+	// Dropped lists the parts that could not be converted.
+	Dropped []string `json:"dropped,omitempty"`
+}
+
+// NewReport returns a new empty report for the given deployment config.
+func NewReport(namespace, name string) *Report {
+	return &Report{DeploymentConfig: namespace + "/" + name}
+}
+
+// Convertedf records the part converted without any loss. Calling this on nil report is a no-op.
+func (r *Report) Convertedf(format string, args ...interface{}) {
+	if r != nil {
+		r.Converted = append(r.Converted, fmt.Sprintf(format, args...))
+	}
+}
+
+// Approximatedf records the part converted with a change in behavior. Calling this on nil report is a no-op.
+func (r *Report) Approximatedf(format string, args ...interface{}) {
+	if r != nil {
+		r.Approximated = append(r.Approximated, fmt.Sprintf(format, args...))
+	}
+}
+
+// Droppedf records the part that could not be converted. Calling this on nil report is a no-op.
+func (r *Report) Droppedf(format string, args ...interface{}) {
+	if r != nil {
+		r.Dropped = append(r.Dropped, fmt.Sprintf(format, args...))
+	}
+}
@@ -0,0 +1,52 @@
+package converter
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultStripVolumePrefixes are the name prefixes of the volumes injected into the pods by the service account
+// admission. Pod templates copied from pods carry them, but the admission adds them to every pod again.
+var DefaultStripVolumePrefixes = []string{"kube-api-access-"}
+
+// stripInjectedVolumes removes the volumes with the given name prefixes from the pod template together with
+// their mounts in all containers. The user-defined volumes, including the projected ones, are kept.
+func stripInjectedVolumes(template *corev1.PodTemplateSpec, prefixes []string, report *Report) {
+	if len(prefixes) == 0 {
+		return
+	}
+	stripped := map[string]bool{}
+	volumes := []corev1.Volume{}
+	for _, volume := range template.Spec.Volumes {
+		if hasPrefix(volume.Name, prefixes) {
+			stripped[volume.Name] = true
+			report.Droppedf("injected volume %q (added to every pod again)", volume.Name)
+			continue
+		}
+		volumes = append(volumes, volume)
+	}
+	if len(stripped) == 0 {
+		return
+	}
+	template.Spec.Volumes = volumes
+
+	for _, container := range templateContainers(template) {
+		mounts := []corev1.VolumeMount{}
+		for _, mount := range container.VolumeMounts {
+			if !stripped[mount.Name] {
+				mounts = append(mounts, mount)
+			}
+		}
+		container.VolumeMounts = mounts
+	}
+}
+
+func hasPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
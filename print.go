@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// printObjects prints the objects serialized in the given format (yaml or json) into the writer.
+// The objects are encoded into their scheme group version, so the apiVersion and kind are always set.
+// Several objects are printed as yaml documents, or wrapped in a v1 List in json, so the output can be
+// decoded as a single json value.
+func printObjects(out io.Writer, format string, objects ...runtime.Object) error {
+	mediaType := "application/json"
+	if format == "yaml" {
+		mediaType = "application/yaml"
+	}
+	info, ok := runtime.SerializerInfoForMediaType(scheme.Codecs.SupportedMediaTypes(), mediaType)
+	if !ok {
+		return fmt.Errorf("unable to find serializer for %q", mediaType)
+	}
+	serializer := info.Serializer
+	if format == "json" && info.PrettySerializer != nil {
+		serializer = info.PrettySerializer
+	}
+
+	if format == "json" && len(objects) > 1 {
+		list := &corev1.List{}
+		for _, obj := range objects {
+			data, err := encodeObject(info.Serializer, obj)
+			if err != nil {
+				return err
+			}
+			list.Items = append(list.Items, runtime.RawExtension{Raw: data})
+		}
+		return scheme.Codecs.EncoderForVersion(serializer, corev1.SchemeGroupVersion).Encode(list, out)
+	}
+
+	for _, obj := range objects {
+		if format == "yaml" {
+			fmt.Fprintln(out, "---")
+		}
+		data, err := encodeObject(serializer, obj)
+		if err != nil {
+			return err
+		}
+		if _, err := out.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeObject serializes the object encoded into its scheme group version.
+func encodeObject(serializer runtime.Serializer, obj runtime.Object) ([]byte, error) {
+	gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, err
+	}
+	return runtime.Encode(scheme.Codecs.EncoderForVersion(serializer, gvks[0].GroupVersion()), obj)
+}
+
+// printObjectNames prints the objects in the <resource>.<group>/<name> form, like kubectl -o name.
+func printObjectNames(out io.Writer, objects ...runtime.Object) error {
+	for _, obj := range objects {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+		if err != nil {
+			return err
+		}
+		resource := strings.ToLower(gvks[0].Kind)
+		if len(gvks[0].Group) > 0 {
+			resource += "." + gvks[0].Group
+		}
+		fmt.Fprintf(out, "%s/%s\n", resource, accessor.GetName())
+	}
+	return nil
+}
+
+// writeObjects writes every object as a separate YAML file named <namespace>-<name>-<kind>.yaml into
+// the directory, which is created when it does not exist. Existing files are only replaced when
+// overwrite is set. The paths of the written files are returned.
+func writeObjects(dir string, overwrite bool, objects ...runtime.Object) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, obj := range objects {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return paths, err
+		}
+		gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+		if err != nil {
+			return paths, err
+		}
+		path := filepath.Join(dir, fmt.Sprintf("%s-%s-%s.yaml", accessor.GetNamespace(), accessor.GetName(), strings.ToLower(gvks[0].Kind)))
+
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if !overwrite {
+			flags |= os.O_EXCL
+		}
+		f, err := os.OpenFile(path, flags, 0644)
+		if os.IsExist(err) {
+			return paths, fmt.Errorf("file %q already exists, use --overwrite to replace it", path)
+		}
+		if err != nil {
+			return paths, err
+		}
+		err = printObjects(f, "yaml", obj)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// stripClusterFields returns copies of the objects without the fields that are specific to the cluster
+// the objects were read from (uid, resourceVersion, creationTimestamp and the owner references pointing
+// to the owner uid), so the objects can be applied to a different cluster.
+func stripClusterFields(objects ...runtime.Object) ([]runtime.Object, error) {
+	result := []runtime.Object{}
+	for _, obj := range objects {
+		obj = obj.DeepCopyObject()
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		accessor.SetUID("")
+		accessor.SetResourceVersion("")
+		accessor.SetSelfLink("")
+		accessor.SetCreationTimestamp(metav1.Time{})
+		accessor.SetOwnerReferences(nil)
+		result = append(result, obj)
+	}
+	return result, nil
+}
+
+// writtenFiles collects the paths of the written object files. It is shared by the concurrent migrations.
+type writtenFiles struct {
+	lock  sync.Mutex
+	paths []string
+}
+
+func (w *writtenFiles) add(paths ...string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.paths = append(w.paths, paths...)
+}
+
+// kustomization is the part of the Kustomize kustomization file written for the output directory.
+type kustomization struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Resources  []string `json:"resources"`
+}
+
+// writeKustomization writes the kustomization.yaml file listing the given object files into the directory,
+// so the directory can be used as a Kustomize base. The path of the written file is returned.
+func writeKustomization(dir string, overwrite bool, paths []string) (string, error) {
+	resources := []string{}
+	for _, path := range paths {
+		resources = append(resources, filepath.Base(path))
+	}
+	sort.Strings(resources)
+	data, err := yaml.Marshal(kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  resources,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, "kustomization.yaml")
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if os.IsExist(err) {
+		return "", fmt.Errorf("file %q already exists, use --overwrite to replace it", path)
+	}
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return path, err
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestPrintObjects(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: "nginx:1"}}},
+			},
+		},
+	}
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "demo"}}
+
+	tests := []struct {
+		name    string
+		format  string
+		objects []kruntime.Object
+		// documents splits the printed output into the serialized objects.
+		documents func(string) []string
+	}{
+		{
+			name:    "yaml",
+			format:  "yaml",
+			objects: []kruntime.Object{deployment, rs},
+			documents: func(output string) []string {
+				return strings.Split(strings.TrimPrefix(output, "---\n"), "---\n")
+			},
+		},
+		{
+			name:    "json",
+			format:  "json",
+			objects: []kruntime.Object{deployment},
+			documents: func(output string) []string {
+				return []string{output}
+			},
+		},
+		{
+			name:    "json list",
+			format:  "json",
+			objects: []kruntime.Object{deployment, rs},
+			documents: func(output string) []string {
+				obj, gvk, err := scheme.Codecs.UniversalDeserializer().Decode([]byte(output), nil, nil)
+				if err != nil {
+					t.Fatalf("unable to decode the printed list: %v\n%s", err, output)
+				}
+				if *gvk != corev1.SchemeGroupVersion.WithKind("List") {
+					t.Fatalf("expected the objects wrapped in a v1 List, got %v", *gvk)
+				}
+				documents := []string{}
+				for _, item := range obj.(*corev1.List).Items {
+					documents = append(documents, string(item.Raw))
+				}
+				return documents
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			if err := printObjects(out, test.format, test.objects...); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			documents := test.documents(out.String())
+			if len(documents) != len(test.objects) {
+				t.Fatalf("expected %d objects, got %d:\n%s", len(test.objects), len(documents), out.String())
+			}
+			for i, document := range documents {
+				obj, gvk, err := scheme.Codecs.UniversalDeserializer().Decode([]byte(document), nil, nil)
+				if err != nil {
+					t.Fatalf("unable to decode the printed object: %v\n%s", err, document)
+				}
+				expected, _, _ := scheme.Scheme.ObjectKinds(test.objects[i])
+				if *gvk != expected[0] {
+					t.Errorf("expected %v, got %v", expected[0], *gvk)
+				}
+				name := obj.(metav1.Object).GetNamespace() + "/" + obj.(metav1.Object).GetName()
+				if expectedName := test.objects[i].(metav1.Object).GetNamespace() + "/" + test.objects[i].(metav1.Object).GetName(); name != expectedName {
+					t.Errorf("expected %s, got %s", expectedName, name)
+				}
+			}
+		})
+	}
+}
+
+func TestPrintObjectNames(t *testing.T) {
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"}}
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"}}
+
+	out := &bytes.Buffer{}
+	if err := printObjectNames(out, deployment, service); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "deployment.apps/web\nservice/web\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestWriteObjects(t *testing.T) {
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"}}
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "demo"}}
+	dir := filepath.Join(t.TempDir(), "manifests")
+
+	paths, err := writeObjects(dir, false, deployment, rs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{filepath.Join(dir, "demo-web-deployment.yaml"), filepath.Join(dir, "demo-web-1-replicaset.yaml")}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("expected files %v, got %v", expected, paths)
+	}
+	data, err := ioutil.ReadFile(paths[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(bytes.TrimPrefix(data, []byte("---\n")), nil, nil)
+	if err != nil {
+		t.Fatalf("unable to decode the written object: %v\n%s", err, data)
+	}
+	if written, ok := obj.(*appsv1.Deployment); !ok || written.Namespace != "demo" || written.Name != "web" {
+		t.Errorf("expected the demo/web deployment, got %#v", obj)
+	}
+
+	if _, err := writeObjects(dir, false, deployment); err == nil || !strings.Contains(err.Error(), "already exists, use --overwrite to replace it") {
+		t.Errorf("expected the existing file error, got %v", err)
+	}
+	if _, err := writeObjects(dir, true, deployment); err != nil {
+		t.Errorf("unexpected error with overwrite: %v", err)
+	}
+}
+
+func TestStripClusterFields(t *testing.T) {
+	controller := true
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "web-1",
+			Namespace:         "demo",
+			UID:               "uid-1",
+			ResourceVersion:   "42",
+			SelfLink:          "/apis/apps/v1/namespaces/demo/replicasets/web-1",
+			CreationTimestamp: metav1.Now(),
+			Labels:            map[string]string{"app": "web"},
+			OwnerReferences:   []metav1.OwnerReference{{Kind: "Deployment", Name: "web", UID: "uid-2", Controller: &controller}},
+		},
+	}
+	original := rs.DeepCopy()
+
+	objects, err := stripClusterFields(rs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stripped := objects[0].(*appsv1.ReplicaSet)
+	if len(stripped.UID) > 0 || len(stripped.ResourceVersion) > 0 || len(stripped.SelfLink) > 0 ||
+		!stripped.CreationTimestamp.IsZero() || len(stripped.OwnerReferences) > 0 {
+		t.Errorf("expected the cluster fields to be stripped, got %#v", stripped.ObjectMeta)
+	}
+	if stripped.Name != "web-1" || stripped.Namespace != "demo" || stripped.Labels["app"] != "web" {
+		t.Errorf("expected the name, namespace and labels to be kept, got %#v", stripped.ObjectMeta)
+	}
+	if !reflect.DeepEqual(rs, original) {
+		t.Errorf("the original object was mutated")
+	}
+
+	out := &bytes.Buffer{}
+	if err := printObjects(out, "yaml", objects...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, field := range []string{"uid:", "resourceVersion:", "ownerReferences:", "creationTimestamp: \"20"} {
+		if strings.Contains(out.String(), field) {
+			t.Errorf("expected no %q in the stripped output:\n%s", field, out.String())
+		}
+	}
+}
+
+func TestWriteKustomization(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "demo-web-deployment.yaml"), filepath.Join(dir, "demo-api-deployment.yaml")}
+
+	path, err := writeKustomization(dir, false, paths)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != filepath.Join(dir, "kustomization.yaml") {
+		t.Errorf("expected kustomization.yaml in the output directory, got %q", path)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n- demo-api-deployment.yaml\n- demo-web-deployment.yaml\n"
+	if string(data) != expected {
+		t.Errorf("expected kustomization:\n%s\ngot:\n%s", expected, data)
+	}
+
+	if _, err := writeKustomization(dir, false, paths); err == nil || !strings.Contains(err.Error(), "already exists, use --overwrite to replace it") {
+		t.Errorf("expected the existing file error, got %v", err)
+	}
+	if _, err := writeKustomization(dir, true, paths[:1]); err != nil {
+		t.Errorf("unexpected error with overwrite: %v", err)
+	}
+}
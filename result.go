@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	resultMigrated = "migrated"
+	resultDryRun   = "dry-run"
+	resultSkipped  = "skipped"
+	resultFailed   = "failed"
+	// resultNotStarted is the status of the deployment configs not processed after a failure with --fail-fast.
+	resultNotStarted = "not-started"
+)
+
+// migrationResult lists the objects created for the deployment config, so the tools running the migration
+// do not have to parse the progress messages.
+type migrationResult struct {
+	DeploymentConfig string   `json:"dc"`
+	Deployment       string   `json:"deployment,omitempty"`
+	ReplicaSets      []string `json:"replicaSets"`
+	Jobs             []string `json:"jobs"`
+	Status           string   `json:"status"`
+	Error            string   `json:"error,omitempty"`
+}
+
+// addObjects records the names of the resulting deployment, replica sets and jobs.
+func (r *migrationResult) addObjects(objects ...kruntime.Object) {
+	if r == nil {
+		return
+	}
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *appsv1.Deployment:
+			r.Deployment = o.Namespace + "/" + o.Name
+		case *appsv1.ReplicaSet:
+			r.ReplicaSets = append(r.ReplicaSets, o.Namespace+"/"+o.Name)
+		case *batchv1.Job:
+			r.Jobs = append(r.Jobs, o.Namespace+"/"+o.Name)
+		}
+	}
+}
+
+// setStatus sets the status from the migration error, unless the migration already set it.
+func (r *migrationResult) setStatus(err error, dryRun bool) {
+	switch {
+	case err != nil:
+		r.Status = resultFailed
+		r.Error = err.Error()
+	case len(r.Status) > 0:
+	case dryRun:
+		r.Status = resultDryRun
+	default:
+		r.Status = resultMigrated
+	}
+}
+
+// printResults prints the migration results as a JSON array.
+func printResults(out io.Writer, results []migrationResult) error {
+	printed := []migrationResult{}
+	for _, result := range results {
+		if len(result.Status) == 0 {
+			result.Status = resultNotStarted
+		}
+		if result.ReplicaSets == nil {
+			result.ReplicaSets = []string{}
+		}
+		if result.Jobs == nil {
+			result.Jobs = []string{}
+		}
+		printed = append(printed, result)
+	}
+	data, err := json.MarshalIndent(printed, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
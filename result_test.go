@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	osappsv1 "github.com/openshift/api/apps/v1"
+)
+
+func TestRunResultFormat(t *testing.T) {
+	dc := newTestDeploymentConfig(2)
+	broken := newTestDeploymentConfig(1)
+	broken.Name = "broken"
+	broken.Spec.Strategy = osappsv1.DeploymentStrategy{Type: "Unknown"}
+
+	tests := []struct {
+		name             string
+		failFast         bool
+		expectedStatuses []string
+	}{
+		{
+			name:             "failure",
+			expectedStatuses: []string{resultFailed, resultMigrated},
+		},
+		{
+			name:             "not started after failure with fail fast",
+			failFast:         true,
+			expectedStatuses: []string{resultFailed, resultNotStarted},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, _ := newTestOptions(t, broken.DeepCopy(), dc.DeepCopy(), newTestReplicationController(dc, 1, 0), newTestReplicationController(dc, 2, 3))
+			m.DeploymentConfigKeys = []string{"demo/broken", "demo/web"}
+			m.ResultFormat = "json"
+			m.FailFast = test.failFast
+			if err := m.Run(); err == nil {
+				t.Fatalf("expected the broken deployment config to fail")
+			}
+
+			results := []migrationResult{}
+			if err := json.Unmarshal(m.Output.(*bytes.Buffer).Bytes(), &results); err != nil {
+				t.Fatalf("unable to decode the results: %v\n%s", err, m.Output.(*bytes.Buffer).String())
+			}
+			if len(results) != 2 {
+				t.Fatalf("expected 2 results, got %#v", results)
+			}
+			for i, result := range results {
+				if result.Status != test.expectedStatuses[i] {
+					t.Errorf("expected %q status %q, got %q", result.DeploymentConfig, test.expectedStatuses[i], result.Status)
+				}
+			}
+			if results[0].DeploymentConfig != "demo/broken" || !strings.Contains(results[0].Error, `has unknown deployment strategy "Unknown"`) {
+				t.Errorf("expected the broken deployment config error, got %#v", results[0])
+			}
+			if test.failFast {
+				return
+			}
+			if web := results[1]; web.Deployment != "demo/web" || len(web.ReplicaSets) != 2 || web.Jobs == nil {
+				t.Errorf("expected the demo/web deployment with 2 replica sets, got %#v", web)
+			}
+		})
+	}
+}
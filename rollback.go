@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/mfojtik/migrate-to-deployment/pkg/converter"
+)
+
+// rollbackAction reverts a single change made by the migration.
+type rollbackAction struct {
+	// description tells what the action reverts, e.g. "deleting deployment "demo/web"".
+	description string
+	revert      func() error
+}
+
+// rollback reverts the changes made by the failed migration in the reverse order. This is best-effort, all
+// the actions are run even when some of them fail and the failures are reported. It returns true when all
+// the changes were reverted.
+func (m *MigrateOptions) rollback(key string, actions []rollbackAction) bool {
+	reverted := true
+	for i := len(actions) - 1; i >= 0; i-- {
+		m.progress("rolling-back", key, fmt.Sprintf("migration failed, %s ...", actions[i].description))
+		if err := actions[i].revert(); err != nil {
+			m.warning("rolling-back", key, fmt.Sprintf("unable to roll back the migration, %s failed: %v", actions[i].description, err))
+			reverted = false
+		}
+	}
+	return reverted
+}
+
+// deleteOptions returns the options deleting the object together with its dependents, e.g. the pods of
+// the deployment or job.
+func deleteOptions() *metav1.DeleteOptions {
+	propagation := metav1.DeletePropagationBackground
+	return &metav1.DeleteOptions{PropagationPolicy: &propagation}
+}
+
+// ignoreNotFound returns nil when the object to revert was already removed.
+func ignoreNotFound(err error) error {
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// restoreDeployment reverts the deployment replaced by the migration to its previous spec.
+func (m *MigrateOptions) restoreDeployment(previous *appsv1.Deployment) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := m.AppsClient.Deployments(previous.Namespace).Get(previous.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		restored := previous.DeepCopy()
+		restored.ResourceVersion = current.ResourceVersion
+		_, err = m.AppsClient.Deployments(previous.Namespace).Update(restored)
+		return err
+	})
+}
+
+// restoreServiceSelector reverts the service selector updated by the migration and removes the original
+// selector annotation.
+func (m *MigrateOptions) restoreServiceSelector(namespace, name string, selector map[string]string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		service, err := m.CoreClient.Services(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		service.Spec.Selector = selector
+		delete(service.Annotations, converter.OriginalSelectorAnnotation)
+		_, err = m.CoreClient.Services(namespace).Update(service)
+		return err
+	})
+}
+
+// restorePodDisruptionBudget recreates the pod disruption budget recreated by the migration with the given
+// original selector, without the original selector annotation.
+func (m *MigrateOptions) restorePodDisruptionBudget(pdb *policyv1beta1.PodDisruptionBudget, selector *metav1.LabelSelector) error {
+	if err := m.PolicyClient.PodDisruptionBudgets(pdb.Namespace).Delete(pdb.Name, &metav1.DeleteOptions{}); ignoreNotFound(err) != nil {
+		return err
+	}
+	restored := recreatedPodDisruptionBudget(pdb, selector)
+	delete(restored.Annotations, converter.OriginalSelectorAnnotation)
+	_, err := m.PolicyClient.PodDisruptionBudgets(pdb.Namespace).Create(restored)
+	return err
+}
+
+// restoreScaleTargetRef points the horizontal pod autoscaler repointed by the migration back to the given
+// original scale target and removes the original scale target annotation.
+func (m *MigrateOptions) restoreScaleTargetRef(namespace, name string, ref autoscalingv1.CrossVersionObjectReference) error {
+	latest, err := m.AutoscalingClient.HorizontalPodAutoscalers(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return m.updateHorizontalPodAutoscaler(latest, func(hpa *autoscalingv1.HorizontalPodAutoscaler) {
+		hpa.Spec.ScaleTargetRef = ref
+		delete(hpa.Annotations, converter.OriginalScaleTargetAnnotation)
+	})
+}
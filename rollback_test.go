@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+
+	osappsv1 "github.com/openshift/api/apps/v1"
+
+	"github.com/mfojtik/migrate-to-deployment/pkg/converter"
+)
+
+func TestMigrateRollback(t *testing.T) {
+	dc := newTestDeploymentConfig(2)
+
+	tests := []struct {
+		name        string
+		reactor     clienttesting.ReactionFunc
+		expectedErr string
+	}{
+		{
+			name: "deployment creation fails",
+			reactor: func(action clienttesting.Action) (bool, kruntime.Object, error) {
+				if action.GetVerb() == "create" && action.GetResource().Resource == "deployments" {
+					return true, nil, fmt.Errorf("admission denied")
+				}
+				return false, nil, nil
+			},
+			expectedErr: "admission denied",
+		},
+		{
+			name: "replication controllers cannot be listed",
+			reactor: func(action clienttesting.Action) (bool, kruntime.Object, error) {
+				if action.GetVerb() == "list" && action.GetResource().Resource == "replicationcontrollers" {
+					return true, nil, fmt.Errorf("connection refused")
+				}
+				return false, nil, nil
+			},
+			expectedErr: "connection refused",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, fake := newTestOptions(t, dc.DeepCopy(), newTestReplicationController(dc, 2, 3))
+			fake.PrependReactor("*", "*", test.reactor)
+
+			err := m.migrate("demo/web")
+			if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+				t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+			}
+			current, err := m.OsAppsClient.DeploymentConfigs("demo").Get("web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if current.Spec.Paused {
+				t.Errorf("expected the deployment config to be unpaused after the failed migration")
+			}
+			if !strings.Contains(m.Output.(*bytes.Buffer).String(), `restoring deployment config "demo/web" pause`) {
+				t.Errorf("expected the rollback to be reported")
+			}
+		})
+	}
+}
+
+func TestMigrateRollbackFailure(t *testing.T) {
+	dc := newTestDeploymentConfig(2)
+	m, fake := newTestOptions(t, dc.DeepCopy())
+	updates := 0
+	fake.PrependReactor("*", "*", func(action clienttesting.Action) (bool, kruntime.Object, error) {
+		switch {
+		case action.GetVerb() == "create" && action.GetResource().Resource == "deployments":
+			return true, nil, fmt.Errorf("admission denied")
+		case action.GetVerb() == "update" && action.GetResource().Resource == "deploymentconfigs":
+			// the first update pauses the deployment config, the second one unpauses it
+			if updates++; updates > 1 {
+				return true, nil, fmt.Errorf("etcd unavailable")
+			}
+		}
+		return false, nil, nil
+	})
+
+	if err := m.migrate("demo/web"); err == nil || err.Error() != "admission denied" {
+		t.Fatalf("expected the migration error to be returned, got %v", err)
+	}
+	if output := m.Output.(*bytes.Buffer).String(); !strings.Contains(output, "unable to roll back the migration") || !strings.Contains(output, "etcd unavailable") {
+		t.Errorf("expected a warning about the failed rollback, got:\n%s", output)
+	}
+}
+
+func TestMigrateRollbackCreatedObjects(t *testing.T) {
+	dc := newTestDeploymentConfig(2)
+	dc.Spec.Strategy.RollingParams = &osappsv1.RollingDeploymentStrategyParams{
+		Pre: &osappsv1.LifecycleHook{
+			FailurePolicy: osappsv1.LifecycleHookFailurePolicyIgnore,
+			ExecNewPod:    &osappsv1.ExecNewPodHook{ContainerName: "web", Command: []string{"/migrate"}},
+		},
+	}
+	dcSelector := map[string]string{converter.DeploymentConfigLabel: "web"}
+	hpaTarget := autoscalingv1.CrossVersionObjectReference{APIVersion: "apps.openshift.io/v1", Kind: "DeploymentConfig", Name: "web"}
+	objects := []kruntime.Object{
+		dc,
+		newTestReplicationController(dc, 1, 0),
+		newTestReplicationController(dc, 2, 3),
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+			Spec:       corev1.ServiceSpec{Selector: dcSelector},
+		},
+		&policyv1beta1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+			Spec:       policyv1beta1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: dcSelector}},
+		},
+		&autoscalingv1.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+			Spec:       autoscalingv1.HorizontalPodAutoscalerSpec{ScaleTargetRef: hpaTarget, MaxReplicas: 5},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		options func(*MigrateOptions, *clienttesting.Fake)
+	}{
+		{
+			name: "deployment creation fails",
+			options: func(m *MigrateOptions, fake *clienttesting.Fake) {
+				fake.PrependReactor("create", "deployments", func(action clienttesting.Action) (bool, kruntime.Object, error) {
+					return true, nil, fmt.Errorf("admission denied")
+				})
+			},
+		},
+		{
+			name: "deployment does not become available",
+			options: func(m *MigrateOptions, fake *clienttesting.Fake) {
+				m.Wait = true
+			},
+		},
+		{
+			name: "migration deadline expires during a slow request",
+			options: func(m *MigrateOptions, fake *clienttesting.Fake) {
+				m.Wait = true
+				fake.PrependReactor("create", "deployments", func(action clienttesting.Action) (bool, kruntime.Object, error) {
+					time.Sleep(2 * m.Timeout)
+					return false, nil, nil
+				})
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, fake := newTestOptions(t, objects...)
+			m.Hooks = "jobs"
+			m.ServiceFixup = "selector"
+			m.MigratePDB = true
+			m.Timeout = 10 * time.Millisecond
+			test.options(m, fake)
+
+			err := m.migrate("demo/web")
+			if _, ok := err.(*rolledBackError); !ok {
+				t.Fatalf("expected rolled back error, got %#v", err)
+			}
+
+			dc, err := m.OsAppsClient.DeploymentConfigs("demo").Get("web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if dc.Spec.Paused {
+				t.Errorf("expected the deployment config to be unpaused")
+			}
+			if _, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{}); !errors.IsNotFound(err) {
+				t.Errorf("expected the deployment to be deleted, got %v", err)
+			}
+			replicaSets, err := m.AppsClient.ReplicaSets("demo").List(metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(replicaSets.Items) > 0 {
+				t.Errorf("expected the replica sets to be deleted, got %d", len(replicaSets.Items))
+			}
+			if _, err := m.BatchClient.Jobs("demo").Get("web-hook-pre", metav1.GetOptions{}); !errors.IsNotFound(err) {
+				t.Errorf("expected the pre hook job to be deleted, got %v", err)
+			}
+			service, err := m.CoreClient.Services("demo").Get("web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(service.Spec.Selector, dcSelector) {
+				t.Errorf("expected the service selector %v restored, got %v", dcSelector, service.Spec.Selector)
+			}
+			pdb, err := m.PolicyClient.PodDisruptionBudgets("demo").Get("web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("expected the pod disruption budget to be restored: %v", err)
+			}
+			if !reflect.DeepEqual(pdb.Spec.Selector.MatchLabels, dcSelector) {
+				t.Errorf("expected the pod disruption budget selector %v restored, got %v", dcSelector, pdb.Spec.Selector.MatchLabels)
+			}
+			hpa, err := m.AutoscalingClient.HorizontalPodAutoscalers("demo").Get("web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if hpa.Spec.ScaleTargetRef != hpaTarget {
+				t.Errorf("expected the scale target %#v restored, got %#v", hpaTarget, hpa.Spec.ScaleTargetRef)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// fieldManagerRoundTripper sets the field manager on every mutating request, so the fields of the
+// created and updated objects are attributed to the field manager.
+type fieldManagerRoundTripper struct {
+	fieldManager string
+	delegate     http.RoundTripper
+}
+
+func (rt *fieldManagerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return rt.delegate.RoundTrip(req)
+	}
+	// Round trippers must not modify the original request
+	req = cloneRequest(req)
+	query := req.URL.Query()
+	query.Set("fieldManager", rt.fieldManager)
+	req.URL.RawQuery = query.Encode()
+	return rt.delegate.RoundTrip(req)
+}
+
+// cloneRequest returns a shallow copy of the request with a copy of the URL.
+func cloneRequest(req *http.Request) *http.Request {
+	clone := new(http.Request)
+	*clone = *req
+	url := *req.URL
+	clone.URL = &url
+	return clone
+}
+
+// contextRoundTripper makes every request with the context of the migration, so the requests are canceled
+// when the migration deadline expires. The typed clients do not accept contexts.
+type contextRoundTripper struct {
+	context  func() context.Context
+	delegate http.RoundTripper
+}
+
+func (rt *contextRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return rt.delegate.RoundTrip(req.WithContext(rt.context()))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFieldManagerRoundTripper(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.Method+" "+r.URL.RawQuery)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &fieldManagerRoundTripper{fieldManager: "migrate-to-deployment", delegate: http.DefaultTransport}}
+	tests := []struct {
+		method        string
+		expectedQuery string
+	}{
+		{method: http.MethodGet, expectedQuery: "GET dryRun=All"},
+		{method: http.MethodPost, expectedQuery: "POST dryRun=All&fieldManager=migrate-to-deployment"},
+		{method: http.MethodPut, expectedQuery: "PUT dryRun=All&fieldManager=migrate-to-deployment"},
+		{method: http.MethodPatch, expectedQuery: "PATCH dryRun=All&fieldManager=migrate-to-deployment"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method, func(t *testing.T) {
+			queries = nil
+			req, err := http.NewRequest(test.method, server.URL+"/apis/apps/v1/namespaces/demo/deployments?dryRun=All", strings.NewReader("{}"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp.Body.Close()
+			if len(queries) != 1 || queries[0] != test.expectedQuery {
+				t.Errorf("expected %q, got %v", test.expectedQuery, queries)
+			}
+			if req.URL.RawQuery != "dryRun=All" {
+				t.Errorf("expected the original request not to be modified, got %q", req.URL.RawQuery)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/spf13/cobra"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	osappsv1 "github.com/openshift/api/apps/v1"
+
+	"github.com/mfojtik/migrate-to-deployment/pkg/converter"
+)
+
+func NewUndoCommand(options *MigrateOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:          "undo",
+		Short:        "Undo the migration by deleting the deployments and restoring the deployment configs",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.color = aurora.NewAurora(!options.NoColor && isTerminal(options.Output) && isTerminal(options.ErrOut))
+			err := options.completeDeploymentConfigArgs(args)
+			if err == nil {
+				err = options.Undo()
+			}
+			if err != nil {
+				fmt.Fprintf(options.ErrOut, options.color.Red("ERROR:").String()+" %v\n", err)
+			}
+			return err
+		},
+	}
+}
+
+// Undo undoes the migration of the deployment configs, it stops at the first deployment config that fails.
+func (m *MigrateOptions) Undo() error {
+	for _, key := range m.DeploymentConfigKeys {
+		if err := m.undo(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// undo deletes the deployment, the replica sets and the lifecycle hook jobs migrated from the deployment config
+// and the generated horizontal pod autoscalers. The services, pod disruption budgets, horizontal pod autoscalers
+// and replication controllers changed by the migration are restored and the deployment config is unpaused,
+// unless it was paused before the migration. Only the objects annotated by the migration are changed.
+func (m *MigrateOptions) undo(key string) error {
+	namespace, name := splitDeploymentConfigKey(key)
+	dc, err := m.OsAppsClient.DeploymentConfigs(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	deployment, err := m.AppsClient.Deployments(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if deployment.Annotations[converter.SourceDeploymentConfigAnnotation] != dc.Name {
+		return fmt.Errorf("deployment %q has no %q annotation pointing to deployment config %q, refusing to delete it",
+			key, converter.SourceDeploymentConfigAnnotation, key)
+	}
+
+	services, err := m.CoreClient.Services(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, service := range services.Items {
+		value, ok := service.Annotations[converter.OriginalSelectorAnnotation]
+		if !ok {
+			continue
+		}
+		selector := map[string]string{}
+		if err := json.Unmarshal([]byte(value), &selector); err != nil {
+			return fmt.Errorf("service %q has invalid %q annotation: %v", service.Namespace+"/"+service.Name, converter.OriginalSelectorAnnotation, err)
+		}
+		if selector[converter.DeploymentConfigLabel] != dc.Name {
+			continue
+		}
+		m.progress("restoring-service", key, fmt.Sprintf("restoring service %q selector ...", m.color.Blue(service.Namespace+"/"+service.Name)))
+		if err := m.restoreServiceSelector(service.Namespace, service.Name, selector); err != nil {
+			return err
+		}
+	}
+
+	pdbs, err := m.PolicyClient.PodDisruptionBudgets(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for i := range pdbs.Items {
+		pdb := &pdbs.Items[i]
+		value, ok := pdb.Annotations[converter.OriginalSelectorAnnotation]
+		if !ok || !reflect.DeepEqual(pdb.Spec.Selector, deployment.Spec.Selector) {
+			continue
+		}
+		selector := &metav1.LabelSelector{}
+		if err := json.Unmarshal([]byte(value), selector); err != nil {
+			return fmt.Errorf("pod disruption budget %q has invalid %q annotation: %v", pdb.Namespace+"/"+pdb.Name, converter.OriginalSelectorAnnotation, err)
+		}
+		m.progress("restoring-pdb", key, fmt.Sprintf("recreating pod disruption budget %q with the original selector ...", m.color.Blue(pdb.Namespace+"/"+pdb.Name)))
+		if err := m.restorePodDisruptionBudget(pdb, selector); err != nil {
+			return err
+		}
+	}
+
+	// The generated horizontal pod autoscalers are deleted, the repointed ones are pointed back to the
+	// deployment config
+	hpas, err := m.AutoscalingClient.HorizontalPodAutoscalers(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, hpa := range hpas.Items {
+		if hpa.Annotations[converter.SourceDeploymentConfigAnnotation] == dc.Name {
+			m.progress("deleting-hpa", key, fmt.Sprintf("deleting horizontal pod autoscaler %q ...", m.color.Blue(hpa.Namespace+"/"+hpa.Name)))
+			if err := m.AutoscalingClient.HorizontalPodAutoscalers(namespace).Delete(hpa.Name, &metav1.DeleteOptions{}); err != nil {
+				return err
+			}
+			continue
+		}
+		value, ok := hpa.Annotations[converter.OriginalScaleTargetAnnotation]
+		if !ok || hpa.Spec.ScaleTargetRef.Kind != "Deployment" || hpa.Spec.ScaleTargetRef.Name != deployment.Name {
+			continue
+		}
+		ref := autoscalingv1.CrossVersionObjectReference{}
+		if err := json.Unmarshal([]byte(value), &ref); err != nil {
+			return fmt.Errorf("horizontal pod autoscaler %q has invalid %q annotation: %v", hpa.Namespace+"/"+hpa.Name, converter.OriginalScaleTargetAnnotation, err)
+		}
+		m.progress("repointing-hpa", key, fmt.Sprintf("repointing horizontal pod autoscaler %q to deployment config %q ...", m.color.Blue(hpa.Namespace+"/"+hpa.Name), m.color.Blue(key)))
+		if err := m.restoreScaleTargetRef(hpa.Namespace, hpa.Name, ref); err != nil {
+			return err
+		}
+	}
+
+	rcs, err := m.findReplicationControllers(dc)
+	if err != nil {
+		return err
+	}
+	for _, rc := range rcs {
+		value, ok := rc.Annotations[converter.OriginalReplicasAnnotation]
+		if !ok {
+			continue
+		}
+		replicas, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("replication controller %q has invalid %q annotation: %v", rc.Namespace+"/"+rc.Name, converter.OriginalReplicasAnnotation, err)
+		}
+		m.progress("scaling-up", key, fmt.Sprintf("scaling replication controller %q up to %d replicas ...", m.color.Blue(rc.Namespace+"/"+rc.Name), replicas))
+		if err := m.restoreReplicationControllerReplicas(rc.Namespace, rc.Name, int32(replicas)); err != nil {
+			return err
+		}
+	}
+
+	jobs, err := m.BatchClient.Jobs(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs.Items {
+		if job.Annotations[converter.SourceDeploymentConfigAnnotation] != dc.Name {
+			continue
+		}
+		m.progress("deleting-hook", key, fmt.Sprintf("deleting lifecycle hook job %q ...", m.color.Blue(job.Namespace+"/"+job.Name)))
+		if err := m.BatchClient.Jobs(namespace).Delete(job.Name, deleteOptions()); err != nil {
+			return err
+		}
+	}
+
+	replicaSets, err := m.AppsClient.ReplicaSets(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, rs := range replicaSets.Items {
+		if rs.Annotations[converter.SourceDeploymentConfigAnnotation] != dc.Name {
+			continue
+		}
+		m.progress("deleting-replica-set", key, fmt.Sprintf("deleting replica set %q ...", m.color.Blue(rs.Namespace+"/"+rs.Name)))
+		if err := m.AppsClient.ReplicaSets(namespace).Delete(rs.Name, &metav1.DeleteOptions{}); err != nil {
+			return err
+		}
+	}
+
+	m.progress("deleting", key, fmt.Sprintf("deleting deployment %q ...", m.color.Blue(key)))
+	if err := m.AppsClient.Deployments(namespace).Delete(name, deleteOptions()); err != nil {
+		return err
+	}
+
+	// The deployment configs migrated before the original paused state was recorded are unpaused
+	wasPaused := dc.Annotations[converter.OriginalPausedAnnotation] == "true"
+	if wasPaused {
+		m.progress("paused", key, fmt.Sprintf("deployment config %q was paused before the migration, leaving it paused", m.color.Blue(key)))
+	} else {
+		m.progress("unpausing", key, fmt.Sprintf("unpausing deployment config %q ...", m.color.Blue(key)))
+	}
+	_, err = m.updateDeploymentConfig(dc, func(dc *osappsv1.DeploymentConfig) {
+		dc.Spec.Paused = wasPaused
+		delete(dc.Annotations, converter.OriginalPausedAnnotation)
+	})
+	return err
+}
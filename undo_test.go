@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+
+	osappsv1 "github.com/openshift/api/apps/v1"
+
+	"github.com/mfojtik/migrate-to-deployment/pkg/converter"
+)
+
+func TestUndo(t *testing.T) {
+	dc := newTestDeploymentConfig(2)
+	m, _ := newTestOptions(t, []kruntime.Object{
+		dc,
+		newTestReplicationController(dc, 1, 0),
+		newTestReplicationController(dc, 2, 3),
+		// The replica set of other deployment must be kept
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "api-1", Namespace: "demo"}},
+	}...)
+	if err := m.migrate("demo/web"); err != nil {
+		t.Fatalf("unexpected migration error: %v", err)
+	}
+
+	if err := m.undo("demo/web"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	restoredDC, err := m.OsAppsClient.DeploymentConfigs("demo").Get("web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if restoredDC.Spec.Paused {
+		t.Errorf("expected the deployment config to be unpaused")
+	}
+	if _, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the deployment to be deleted, got %v", err)
+	}
+	replicaSets, err := m.AppsClient.ReplicaSets("demo").List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(replicaSets.Items) != 1 || replicaSets.Items[0].Name != "api-1" {
+		t.Errorf("expected only the api-1 replica set to be kept, got %#v", replicaSets.Items)
+	}
+}
+
+func TestUndoGeneratedHorizontalPodAutoscaler(t *testing.T) {
+	m, _ := newTestOptions(t, newTestDeploymentConfig(1),
+		// The autoscaler of other deployment must be kept
+		&autoscalingv1.HorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "demo"}})
+	m.MaxReplicas = 5
+	if err := m.migrate("demo/web"); err != nil {
+		t.Fatalf("unexpected migration error: %v", err)
+	}
+
+	if err := m.undo("demo/web"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	hpas, err := m.AutoscalingClient.HorizontalPodAutoscalers("demo").List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hpas.Items) != 1 || hpas.Items[0].Name != "api" {
+		t.Errorf("expected only the api horizontal pod autoscaler to be kept, got %#v", hpas.Items)
+	}
+}
+
+func TestUndoRefusesDeploymentNotMigrated(t *testing.T) {
+	dc := newTestDeploymentConfig(2)
+	dc.Spec.Paused = true
+	m, fake := newTestOptions(t, dc, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"}})
+
+	err := m.undo("demo/web")
+	expectedErr := `deployment "demo/web" has no "migrate-to-deployment/source-dc" annotation pointing to deployment config "demo/web", refusing to delete it`
+	if err == nil || err.Error() != expectedErr {
+		t.Fatalf("expected error %q, got %v", expectedErr, err)
+	}
+	for _, action := range fake.Actions() {
+		if action.GetVerb() != "get" {
+			t.Errorf("expected no changes, got %s %s", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+}
+
+func TestUndoRestoresMigratedObjects(t *testing.T) {
+	dcSelector := map[string]string{converter.DeploymentConfigLabel: "web"}
+	hpaTarget := autoscalingv1.CrossVersionObjectReference{APIVersion: "apps.openshift.io/v1", Kind: "DeploymentConfig", Name: "web"}
+
+	tests := []struct {
+		name   string
+		paused bool
+	}{
+		{name: "migrated deployment config is restored"},
+		{name: "paused deployment config stays paused", paused: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dc := newTestDeploymentConfig(2)
+			dc.Spec.Paused = test.paused
+			dc.Spec.Strategy.RollingParams = &osappsv1.RollingDeploymentStrategyParams{
+				Pre: &osappsv1.LifecycleHook{
+					FailurePolicy: osappsv1.LifecycleHookFailurePolicyAbort,
+					ExecNewPod:    &osappsv1.ExecNewPodHook{ContainerName: "web", Command: []string{"/migrate"}},
+				},
+			}
+			m, fake := newTestOptions(t, []kruntime.Object{
+				dc,
+				newTestReplicationController(dc, 2, 3),
+				&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+					Spec:       corev1.ServiceSpec{Selector: dcSelector},
+				},
+				&policyv1beta1.PodDisruptionBudget{
+					ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+					Spec:       policyv1beta1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: dcSelector}},
+				},
+				&autoscalingv1.HorizontalPodAutoscaler{
+					ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+					Spec:       autoscalingv1.HorizontalPodAutoscalerSpec{ScaleTargetRef: hpaTarget, MaxReplicas: 5},
+				},
+			}...)
+			m.Hooks = "jobs"
+			m.ServiceFixup = "selector"
+			m.MigratePDB = true
+			m.Wait = true
+			completeRollouts(fake)
+			if err := m.migrate("demo/web"); err != nil {
+				t.Fatalf("unexpected migration error: %v", err)
+			}
+
+			if err := m.undo("demo/web"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			restoredDC, err := m.OsAppsClient.DeploymentConfigs("demo").Get("web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if restoredDC.Spec.Paused != test.paused {
+				t.Errorf("expected the deployment config paused %t, got %t", test.paused, restoredDC.Spec.Paused)
+			}
+			if _, ok := restoredDC.Annotations[converter.OriginalPausedAnnotation]; ok {
+				t.Errorf("expected the original paused annotation removed, got %v", restoredDC.Annotations)
+			}
+			if _, err := m.AppsClient.Deployments("demo").Get("web", metav1.GetOptions{}); !errors.IsNotFound(err) {
+				t.Errorf("expected the deployment to be deleted, got %v", err)
+			}
+			if _, err := m.BatchClient.Jobs("demo").Get("web-hook-pre", metav1.GetOptions{}); !errors.IsNotFound(err) {
+				t.Errorf("expected the pre hook job to be deleted, got %v", err)
+			}
+			service, err := m.CoreClient.Services("demo").Get("web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(service.Spec.Selector, dcSelector) {
+				t.Errorf("expected the service selector %v restored, got %v", dcSelector, service.Spec.Selector)
+			}
+			pdb, err := m.PolicyClient.PodDisruptionBudgets("demo").Get("web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("expected the pod disruption budget to be restored: %v", err)
+			}
+			if !reflect.DeepEqual(pdb.Spec.Selector.MatchLabels, dcSelector) {
+				t.Errorf("expected the pod disruption budget selector %v restored, got %v", dcSelector, pdb.Spec.Selector.MatchLabels)
+			}
+			hpa, err := m.AutoscalingClient.HorizontalPodAutoscalers("demo").Get("web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if hpa.Spec.ScaleTargetRef != hpaTarget {
+				t.Errorf("expected the scale target %#v restored, got %#v", hpaTarget, hpa.Spec.ScaleTargetRef)
+			}
+			rc, err := m.CoreClient.ReplicationControllers("demo").Get("web-2", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rc.Spec.Replicas == nil || *rc.Spec.Replicas != 3 {
+				t.Errorf("expected the replication controller scaled back to 3 replicas, got %v", rc.Spec.Replicas)
+			}
+			if _, ok := rc.Annotations[converter.OriginalReplicasAnnotation]; ok {
+				t.Errorf("expected the original replicas annotation removed, got %v", rc.Annotations)
+			}
+		})
+	}
+}
+
+func TestUndoCommand(t *testing.T) {
+	tests := []struct {
+		name             string
+		args             []string
+		migrated         bool
+		expectedExitCode int
+		expectedErr      string
+	}{
+		{
+			name:     "migrated",
+			args:     []string{"demo/web"},
+			migrated: true,
+		},
+		{
+			name:             "no deployment configs",
+			expectedExitCode: exitCodeValidation,
+			expectedErr:      "ERROR: deployment config name(s) must be specified",
+		},
+		{
+			name:             "not migrated",
+			args:             []string{"demo/web"},
+			expectedExitCode: exitCodeError,
+			expectedErr:      `ERROR: deployments.apps "web" not found`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, _ := newTestOptions(t, newTestDeploymentConfig(1))
+			if test.migrated {
+				if err := m.migrate("demo/web"); err != nil {
+					t.Fatalf("unexpected migration error: %v", err)
+				}
+			}
+			m.loadClients = func() error { return nil }
+			cmd := NewUndoCommand(m)
+			cmd.SetOutput(&bytes.Buffer{})
+			cmd.SetArgs(test.args)
+
+			err := cmd.Execute()
+			if code := exitCode(err); code != test.expectedExitCode {
+				t.Errorf("expected exit code %d, got %d (%v)", test.expectedExitCode, code, err)
+			}
+			if errOut := m.ErrOut.(*bytes.Buffer).String(); !strings.Contains(errOut, test.expectedErr) {
+				t.Errorf("expected error output containing %q, got %q", test.expectedErr, errOut)
+			}
+		})
+	}
+}
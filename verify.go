@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func NewVerifyCommand(options *MigrateOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:          "verify",
+		Short:        "Verify the deployment configs were migrated to available deployments",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.color = aurora.NewAurora(!options.NoColor && isTerminal(options.Output) && isTerminal(options.ErrOut))
+			err := options.completeDeploymentConfigArgs(args)
+			if err == nil {
+				err = options.Verify()
+			}
+			if err != nil {
+				fmt.Fprintf(options.ErrOut, options.color.Red("ERROR:").String()+" %v\n", err)
+			}
+			return err
+		},
+	}
+}
+
+// Verify checks the deployment configs were migrated: the deployment exists, is not paused and has all
+// replicas available, while the deployment config is paused or scaled down and its replication controllers
+// are scaled down.
+func (m *MigrateOptions) Verify() error {
+	failed := []string{}
+	for _, key := range m.DeploymentConfigKeys {
+		ok, err := m.verify(key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			failed = append(failed, key)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("deployment configs not migrated: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// verify prints the result of every check for the deployment config and returns true when all checks pass.
+func (m *MigrateOptions) verify(key string) (bool, error) {
+	namespace, name := splitDeploymentConfigKey(key)
+	passed := true
+	check := func(ok bool, message string) {
+		result := m.color.Green("PASS").String()
+		if !ok {
+			result = m.color.Red("FAIL").String()
+			passed = false
+		}
+		fmt.Fprintf(m.Output, "%s %s: %s\n", result, m.color.Blue(key), message)
+	}
+
+	dc, err := m.OsAppsClient.DeploymentConfigs(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	check(dc.Spec.Paused || dc.Spec.Replicas == 0, fmt.Sprintf("deployment config is paused or scaled down (paused=%t, replicas=%d)",
+		dc.Spec.Paused, dc.Spec.Replicas))
+
+	rcs, err := m.findReplicationControllers(dc)
+	if err != nil {
+		return false, err
+	}
+	running := int32(0)
+	for _, rc := range rcs {
+		if rc.Spec.Replicas != nil {
+			running += *rc.Spec.Replicas
+		}
+	}
+	check(running == 0, fmt.Sprintf("deployment config replication controllers are scaled down (%d replicas)", running))
+
+	deployment, err := m.AppsClient.Deployments(namespace).Get(name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		check(false, "deployment exists")
+		return passed, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	check(true, "deployment exists")
+	check(!deployment.Spec.Paused, "deployment is not paused")
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	check(deployment.Status.AvailableReplicas >= replicas && deployment.Status.UpdatedReplicas >= replicas,
+		fmt.Sprintf("deployment has all replicas available (%d of %d)", deployment.Status.AvailableReplicas, replicas))
+
+	return passed, nil
+}
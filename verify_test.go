@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestVerify(t *testing.T) {
+	dc := newTestDeploymentConfig(2)
+	dc.Spec.Paused = true
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3},
+	}
+	pausedDeployment := deployment.DeepCopy()
+	pausedDeployment.Spec.Paused = true
+	pausedDeployment.Status = appsv1.DeploymentStatus{}
+
+	tests := []struct {
+		name           string
+		objects        []kruntime.Object
+		expectedPassed bool
+		expectedOutput []string
+	}{
+		{
+			name:           "migrated",
+			objects:        []kruntime.Object{dc, deployment, newTestReplicationController(dc, 2, 0)},
+			expectedPassed: true,
+			expectedOutput: []string{
+				"PASS demo/web: deployment config is paused or scaled down (paused=true, replicas=3)",
+				"PASS demo/web: deployment config replication controllers are scaled down (0 replicas)",
+				"PASS demo/web: deployment exists",
+				"PASS demo/web: deployment is not paused",
+				"PASS demo/web: deployment has all replicas available (3 of 3)",
+			},
+		},
+		{
+			name:    "half-finished migration",
+			objects: []kruntime.Object{newTestDeploymentConfig(2), pausedDeployment},
+			expectedOutput: []string{
+				"FAIL demo/web: deployment config is paused or scaled down (paused=false, replicas=3)",
+				"FAIL demo/web: deployment is not paused",
+				"FAIL demo/web: deployment has all replicas available (0 of 3)",
+			},
+		},
+		{
+			name:           "replication controllers still running",
+			objects:        []kruntime.Object{dc, deployment, newTestReplicationController(dc, 2, 3)},
+			expectedOutput: []string{"FAIL demo/web: deployment config replication controllers are scaled down (3 replicas)"},
+		},
+		{
+			name:           "deployment missing",
+			objects:        []kruntime.Object{dc},
+			expectedOutput: []string{"FAIL demo/web: deployment exists"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, _ := newTestOptions(t, test.objects...)
+			m.DeploymentConfigKeys = []string{"demo/web"}
+			err := m.Verify()
+			if test.expectedPassed && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !test.expectedPassed && (err == nil || err.Error() != "deployment configs not migrated: demo/web") {
+				t.Fatalf("expected the not migrated error, got %v", err)
+			}
+			output := m.Output.(*bytes.Buffer).String()
+			for _, expected := range test.expectedOutput {
+				if !strings.Contains(output, expected) {
+					t.Errorf("expected output containing %q, got:\n%s", expected, output)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyCommand(t *testing.T) {
+	dc := newTestDeploymentConfig(2)
+	dc.Spec.Paused = true
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3},
+	}
+
+	tests := []struct {
+		name             string
+		args             []string
+		objects          []kruntime.Object
+		expectedExitCode int
+		expectedErr      string
+	}{
+		{
+			name:    "migrated",
+			args:    []string{"demo/web"},
+			objects: []kruntime.Object{dc, deployment},
+		},
+		{
+			name:             "no deployment configs",
+			expectedExitCode: exitCodeValidation,
+			expectedErr:      "ERROR: deployment config name(s) must be specified",
+		},
+		{
+			name:             "invalid deployment config name",
+			args:             []string{"demo/dc/web/v1"},
+			expectedExitCode: exitCodeValidation,
+			expectedErr:      `ERROR: invalid deployment config name "demo/dc/web/v1"`,
+		},
+		{
+			name:             "not migrated",
+			args:             []string{"demo/web"},
+			objects:          []kruntime.Object{dc},
+			expectedExitCode: exitCodeError,
+			expectedErr:      "ERROR: deployment configs not migrated: demo/web",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, _ := newTestOptions(t, test.objects...)
+			m.loadClients = func() error { return nil }
+			cmd := NewVerifyCommand(m)
+			cmd.SetOutput(&bytes.Buffer{})
+			cmd.SetArgs(test.args)
+
+			err := cmd.Execute()
+			if code := exitCode(err); code != test.expectedExitCode {
+				t.Errorf("expected exit code %d, got %d (%v)", test.expectedExitCode, code, err)
+			}
+			if errOut := m.ErrOut.(*bytes.Buffer).String(); !strings.Contains(errOut, test.expectedErr) {
+				t.Errorf("expected error output containing %q, got %q", test.expectedErr, errOut)
+			}
+		})
+	}
+}